@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"scriptguard/backend/database"
 	"scriptguard/backend/models"
@@ -65,6 +66,8 @@ type App struct {
 	scheduler *services.SchedulerService
 	notifier  *services.NotifierService
 	cleanup   *services.CleanupService
+	digest    *services.DigestService
+	redactor  *services.Redactor
 }
 
 func NewApp() *App {
@@ -88,9 +91,28 @@ func (a *App) ServiceStartup(ctx context.Context, options application.ServiceOpt
 	// 初始化服务
 	a.conda = services.NewCondaService()
 	a.executor = services.NewExecutorService()
-	a.notifier = services.NewNotifierService("", "")
 
-	// 启动时从配置表加载 webhook
+	// 日志文件（溢出暂存等）放在用户数据目录下
+	dataDir, err := database.GetDefaultDataDir()
+	if err != nil {
+		return fmt.Errorf("获取数据目录失败: %w", err)
+	}
+	a.executor.SetLogDir(filepath.Join(dataDir, "logs"))
+	a.executor.RecoverSpilledLogs()
+	a.executor.SetArtifactDir(filepath.Join(dataDir, "artifacts"))
+
+	a.notifier = services.NewNotifierService()
+
+	// 敏感信息脱敏：日志与告警共用同一套规则
+	a.redactor = services.NewRedactor()
+	a.executor.SetRedactor(a.redactor)
+	a.notifier.SetRedactor(a.redactor)
+	a.executor.SetStallHandler(a.notifier.NotifyStall)
+	if err := a.reloadRedactorConfig(); err != nil {
+		log.Printf("加载脱敏配置失败: %v，仅使用内置规则", err)
+	}
+
+	// 启动时同步旧版 webhook 配置并加载通知渠道
 	if err := a.reloadNotifierConfig(); err != nil {
 		return err
 	}
@@ -99,18 +121,24 @@ func (a *App) ServiceStartup(ctx context.Context, options application.ServiceOpt
 	if err := a.reloadExecutorConfig(); err != nil {
 		return err
 	}
+	if err := a.executor.LoadPools(); err != nil {
+		return err
+	}
 
 	a.scheduler = services.NewSchedulerService(a.executor, a.notifier)
 	a.cleanup = services.NewCleanupService()
+	a.digest = services.NewDigestService(a.notifier)
 
-	// 启动调度器和清理服务
+	// 启动调度器、清理服务、告警投递和执行汇总
 	a.scheduler.Start()
 	a.cleanup.Start()
+	a.notifier.Start()
+	a.digest.Start()
 
 	// 加载已有任务
 	a.loadTasks()
 
-	// 启动日志流转发（将executor的logChan转发到前端Event）
+	// 启动日志流转发（将executor的实时上报事件转发到前端Event）
 	go a.startLogStreaming()
 
 	return nil
@@ -124,20 +152,45 @@ func (a *App) ServiceShutdown() error {
 	if a.cleanup != nil {
 		a.cleanup.Stop()
 	}
+	if a.digest != nil {
+		a.digest.Stop()
+	}
+	if a.notifier != nil {
+		a.notifier.Stop()
+	}
 	return database.CloseDB()
 }
 
-// reloadNotifierConfig 从配置表加载告警配置
+// reloadNotifierConfig 将配置表中的 webhook 同步为通知渠道，并重新加载全部渠道
 func (a *App) reloadNotifierConfig() error {
 	config, err := a.GetAllConfig()
 	if err != nil {
 		return err
 	}
-	a.notifier.SetWebhooks(
+	if err := services.SyncLegacyChannels(
 		config[models.ConfigKeyDingTalkWebhook],
 		config[models.ConfigKeyWeComWebhook],
+	); err != nil {
+		return fmt.Errorf("同步告警配置失败: %w", err)
+	}
+	if minutes, err := strconv.Atoi(strings.TrimSpace(config[models.ConfigKeyNotifyDedupMinutes])); err == nil && minutes >= 0 {
+		a.notifier.SetDedupWindow(time.Duration(minutes) * time.Minute)
+	}
+	return a.notifier.LoadChannels()
+}
+
+// reloadRedactorConfig 从配置表加载脱敏规则
+func (a *App) reloadRedactorConfig() error {
+	config, err := a.GetAllConfig()
+	if err != nil {
+		return err
+	}
+	builtin := !strings.EqualFold(strings.TrimSpace(config[models.ConfigKeyRedactBuiltinEnabled]), "false")
+	return a.redactor.SetRules(
+		services.ParseRedactRules(config[models.ConfigKeyRedactSecrets]),
+		services.ParseRedactRules(config[models.ConfigKeyRedactPatterns]),
+		builtin,
 	)
-	return nil
 }
 
 // SG-019: reloadExecutorConfig 从配置表加载执行器配置
@@ -176,6 +229,24 @@ func (a *App) reloadExecutorConfig() error {
 		}
 	}
 
+	// 加载日志存储后端
+	if val := strings.TrimSpace(config[models.ConfigKeyLogStorage]); val != "" {
+		if err := validateLogStorage(val); err != nil {
+			log.Printf("加载日志存储配置失败: %v，使用数据库存储", err)
+		} else {
+			a.executor.SetLogStorage(val)
+			log.Printf("已加载日志存储配置: %s", val)
+		}
+	}
+
+	return nil
+}
+
+// validateLogStorage 校验日志存储后端取值
+func validateLogStorage(value string) error {
+	if value != models.LogStorageDatabase && value != models.LogStorageFile {
+		return fmt.Errorf("%s 仅支持 %s 或 %s", models.ConfigKeyLogStorage, models.LogStorageDatabase, models.LogStorageFile)
+	}
 	return nil
 }
 
@@ -220,6 +291,30 @@ func (a *App) CreateTask(task models.Task) error {
 	if err := validateCronExprs(task.CronExprs); err != nil {
 		return err
 	}
+	if err := services.ValidateLaunchTemplates(&task); err != nil {
+		return err
+	}
+	if err := services.ValidateArtifactPatterns(task.ArtifactPatterns); err != nil {
+		return err
+	}
+	if err := services.ValidateSuccessRules(task.SuccessRules); err != nil {
+		return err
+	}
+	if err := services.ValidateStallSettings(&task); err != nil {
+		return err
+	}
+	if err := services.ValidateResourceLimits(&task); err != nil {
+		return err
+	}
+	if err := services.ValidateQueueSettings(&task, a.executor.GetMaxConcurrency()); err != nil {
+		return err
+	}
+	if err := services.ValidateTaskPools(&task); err != nil {
+		return err
+	}
+	if err := services.ValidateNotifyRules(task.NotifyRules); err != nil {
+		return err
+	}
 
 	db := database.GetDB()
 
@@ -247,6 +342,30 @@ func (a *App) UpdateTask(task models.Task) error {
 	if err := validateCronExprs(task.CronExprs); err != nil {
 		return err
 	}
+	if err := services.ValidateLaunchTemplates(&task); err != nil {
+		return err
+	}
+	if err := services.ValidateArtifactPatterns(task.ArtifactPatterns); err != nil {
+		return err
+	}
+	if err := services.ValidateSuccessRules(task.SuccessRules); err != nil {
+		return err
+	}
+	if err := services.ValidateStallSettings(&task); err != nil {
+		return err
+	}
+	if err := services.ValidateResourceLimits(&task); err != nil {
+		return err
+	}
+	if err := services.ValidateQueueSettings(&task, a.executor.GetMaxConcurrency()); err != nil {
+		return err
+	}
+	if err := services.ValidateTaskPools(&task); err != nil {
+		return err
+	}
+	if err := services.ValidateNotifyRules(task.NotifyRules); err != nil {
+		return err
+	}
 
 	db := database.GetDB()
 
@@ -296,21 +415,28 @@ func (a *App) DeleteTask(taskID string) error {
 
 // ExecuteTaskNow 立即执行任务
 func (a *App) ExecuteTaskNow(taskID string) (*models.Execution, error) {
-	// SG-020: 立即执行也需要检查并发限制
-	if !a.executor.TryExecute() {
-		return nil, fmt.Errorf("当前并发任务数已达上限，请稍后重试")
-	}
-	defer a.executor.ReleaseExecution()
-
 	var task models.Task
 	if err := database.GetDB().First(&task, "id = ?", taskID).Error; err != nil {
 		return nil, err
 	}
 
+	// SG-020: 立即执行也需要检查并发限制（不排队）
+	if !a.executor.TryExecute(&task) {
+		return nil, fmt.Errorf("当前并发槽位不足，请稍后重试")
+	}
+	defer a.executor.ReleaseExecution(&task)
+
+	stopWatch := a.notifier.WatchLongRunning(&task)
 	execution, err := a.executor.ExecuteScript(&task)
+	stopWatch()
 
 	// 无论成功失败都记录执行历史，并检查写库错误
-	if dbErr := database.GetDB().Create(execution).Error; dbErr != nil {
+	dbErr := database.GetDB().Create(execution).Error
+
+	// 与定时执行一致，按任务的通知规则发送失败、成功、恢复等通知
+	a.notifier.NotifyExecution(&task, execution, err)
+
+	if dbErr != nil {
 		if err == nil {
 			// 脚本执行成功，但历史写入失败
 			err = fmt.Errorf("脚本执行成功，但写入执行历史失败: %w", dbErr)
@@ -323,7 +449,7 @@ func (a *App) ExecuteTaskNow(taskID string) (*models.Execution, error) {
 	return execution, err
 }
 
-// GetExecutions 获取执行历史
+// GetExecutions 获取执行历史（仅返回最新 N 条，完整历史请使用 ListExecutions 分页）
 func (a *App) GetExecutions(taskID string, limit int) ([]models.Execution, error) {
 	// SG-018: 服务端上限保护
 	const maxLimit = 5000
@@ -344,7 +470,12 @@ func (a *App) GetExecutions(taskID string, limit int) ([]models.Execution, error
 	return executions, err
 }
 
-// GetLogs 获取日志
+// ListExecutions 分页获取执行历史（游标分页，支持按状态与时间范围筛选）
+func (a *App) ListExecutions(query models.ExecutionQuery) (*models.ExecutionPage, error) {
+	return services.ListExecutions(query)
+}
+
+// GetLogs 获取日志（仅返回最新 N 条，完整日志请使用 ListLogs 分页）
 func (a *App) GetLogs(executionID string, taskID string, limit int) ([]models.Log, error) {
 	// SG-018: 服务端上限保护
 	const maxLimit = 5000
@@ -352,35 +483,149 @@ func (a *App) GetLogs(executionID string, taskID string, limit int) ([]models.Lo
 		limit = maxLimit
 	}
 
-	var logs []models.Log
+	// 取最新 N 条并按时间 ASC 返回（透明合并数据库与文件存储后端）
+	return services.QueryRecentLogs(executionID, taskID, limit)
+}
 
-	query := database.GetDB()
-	if executionID != "" {
-		query = query.Where("execution_id = ?", executionID)
-	} else if taskID != "" {
-		query = query.Where("task_id = ?", taskID)
-	}
+// ListLogs 分页获取日志（游标分页，支持按级别与时间范围筛选）
+func (a *App) ListLogs(query models.LogQuery) (*models.LogPage, error) {
+	return services.ListLogs(query)
+}
+
+// SearchLogs 全文搜索执行日志（按时间倒序，每页 100 条；cursor 传上一页返回的 next_cursor）
+// 只检索数据库存储的日志，文件存储的执行不参与检索，其数量见 skipped_file_executions
+func (a *App) SearchLogs(query string, taskIDs []string, levels []string, timeRange models.TimeRange, cursor string) (*models.LogSearchResult, error) {
+	return services.SearchLogs(services.LogSearchQuery{
+		Query:     query,
+		TaskIDs:   taskIDs,
+		Levels:    levels,
+		TimeRange: timeRange,
+		Cursor:    cursor,
+	})
+}
 
-	// 按时间倒序取最新 N 条，再在内存中反转成正序
-	if err := query.Order("timestamp DESC").Limit(limit).Find(&logs).Error; err != nil {
+// GetQueuedRuns 获取排队等待并发槽位的执行（按授予顺序）
+func (a *App) GetQueuedRuns() []services.QueuedRun {
+	return a.executor.QueuedRuns()
+}
+
+// GetPools 获取全部资源池（含当前占用名额）
+func (a *App) GetPools() ([]models.Pool, error) {
+	var pools []models.Pool
+	if err := database.GetDB().Order("name").Find(&pools).Error; err != nil {
 		return nil, err
 	}
-	// 反转切片：让返回结果按时间 ASC
-	for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
-		logs[i], logs[j] = logs[j], logs[i]
+	for i := range pools {
+		pools[i].Running = a.executor.PoolUsage(pools[i].Name)
+	}
+	return pools, nil
+}
+
+// CreatePool 创建资源池
+func (a *App) CreatePool(pool models.Pool) error {
+	if err := services.ValidatePool(&pool); err != nil {
+		return err
+	}
+	var count int64
+	if err := database.GetDB().Model(&models.Pool{}).Where("name = ?", pool.Name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("资源池已存在: %s", pool.Name)
+	}
+	if err := database.GetDB().Create(&pool).Error; err != nil {
+		return err
+	}
+	return a.executor.LoadPools()
+}
+
+// UpdatePool 更新资源池的最大并发数与描述（名称不可修改）
+func (a *App) UpdatePool(pool models.Pool) error {
+	if err := services.ValidatePool(&pool); err != nil {
+		return err
+	}
+	// 重新校验引用该资源池的任务，仍有任务无法被授予时拒绝修改
+	tasks, err := services.TasksExceedingCapacity(a.executor.GetMaxConcurrency(), pool.Name)
+	if err != nil {
+		return err
+	}
+	if len(tasks) > 0 {
+		return fmt.Errorf("以下任务的权重超过最大并发数，请先调整任务权重: %s", strings.Join(tasks, "、"))
+	}
+	result := database.GetDB().Model(&models.Pool{}).Where("name = ?", pool.Name).Updates(map[string]any{
+		"max_running": pool.MaxRunning,
+		"description": pool.Description,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("资源池不存在: %s", pool.Name)
+	}
+	return a.executor.LoadPools()
+}
+
+// DeletePool 删除资源池（仍被任务引用时拒绝删除）
+func (a *App) DeletePool(name string) error {
+	tasks, err := services.TasksUsingPool(name)
+	if err != nil {
+		return err
+	}
+	if len(tasks) > 0 {
+		return fmt.Errorf("资源池仍被以下任务使用: %s", strings.Join(tasks, "、"))
+	}
+	if err := database.GetDB().Delete(&models.Pool{}, "name = ?", name).Error; err != nil {
+		return err
+	}
+	return a.executor.LoadPools()
+}
+
+// GetResourceTrend 获取任务最近 limit 次执行的资源占用趋势（CPU、内存峰值、I/O）
+func (a *App) GetResourceTrend(taskID string, limit int) ([]models.ResourcePoint, error) {
+	return services.ResourceTrend(taskID, limit)
+}
+
+// ListArtifacts 获取某次执行收集的产物
+func (a *App) ListArtifacts(executionID string) ([]models.Artifact, error) {
+	var artifacts []models.Artifact
+	err := database.GetDB().
+		Where("execution_id = ?", executionID).
+		Order("name ASC").
+		Find(&artifacts).Error
+	return artifacts, err
+}
+
+// OpenArtifact 使用系统默认程序打开产物
+func (a *App) OpenArtifact(artifactID string) error {
+	var artifact models.Artifact
+	if err := database.GetDB().First(&artifact, "id = ?", artifactID).Error; err != nil {
+		return err
 	}
-	return logs, nil
+	if _, err := os.Stat(artifact.Path); err != nil {
+		return fmt.Errorf("产物文件不存在或已被清理: %w", err)
+	}
+	return application.Get().Browser.OpenFile(artifact.Path)
 }
 
+// executionReportEvent 推送到前端的执行实时上报事件（进度、指标、执行结束）
+const executionReportEvent = "execution:report"
+
 // startLogStreaming 启动日志流转发
 func (a *App) startLogStreaming() {
 	logChan := a.executor.GetLogChannel()
 	for logMsg := range logChan {
-		// 将日志消息转发到前端（通过Wails Events）
-		// 注意：Wails 3的Events API可能需要调整
-		// application.EmitEvent(a.ctx, "log:stream", logMsg)
-		// 暂时注释，因为需要application实例的引用
-		_ = logMsg
+		// 普通日志行已落库，前端按需查询；进度、指标与执行结束事件实时推送
+		switch logMsg.Level {
+		case services.StreamEventProgress, services.StreamEventMetric, services.StreamEventFinished:
+			application.Get().Event.Emit(executionReportEvent, map[string]any{
+				"type":         logMsg.Level,
+				"execution_id": logMsg.ExecutionID,
+				"task_id":      logMsg.TaskID,
+				"timestamp":    logMsg.Timestamp,
+				"content":      logMsg.Content,
+				"fields":       logMsg.Fields,
+			})
+		}
 	}
 }
 
@@ -424,6 +669,74 @@ func (a *App) UpdateConfig(key, value string) error {
 		}
 	}
 
+	// 产物保留天数校验
+	if key == models.ConfigKeyArtifactRetentionDays {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 1 {
+			return fmt.Errorf("%s 必须为正整数（单位：天）", models.ConfigKeyArtifactRetentionDays)
+		}
+	}
+
+	// 最大并发数校验：调小后不能有任务的权重超过新值，否则其排队的执行永远无法获得槽位
+	if key == models.ConfigKeyMaxConcurrency {
+		maxConcurrency, err := strconv.Atoi(value)
+		if err != nil || maxConcurrency < 1 {
+			return fmt.Errorf("%s 必须为正整数", models.ConfigKeyMaxConcurrency)
+		}
+		tasks, err := services.TasksExceedingCapacity(maxConcurrency, "")
+		if err != nil {
+			return err
+		}
+		if len(tasks) > 0 {
+			return fmt.Errorf("以下任务的权重超过最大并发数 %d，请先调整任务权重: %s", maxConcurrency, strings.Join(tasks, "、"))
+		}
+	}
+
+	// 告警去重窗口校验
+	if key == models.ConfigKeyNotifyDedupMinutes {
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes < 0 {
+			return fmt.Errorf("%s 必须为非负整数（单位：分钟，0 表示不去重）", models.ConfigKeyNotifyDedupMinutes)
+		}
+	}
+
+	// 执行汇总配置校验
+	if key == models.ConfigKeyDigestTime {
+		if _, _, err := services.ParseDigestTime(value); err != nil {
+			return err
+		}
+	}
+	if key == models.ConfigKeyDigestChannels {
+		if err := services.ValidateDigestChannels(value); err != nil {
+			return err
+		}
+	}
+
+	// 清空旧版 webhook 会删除对应渠道，渠道仍被引用时拒绝（在保存配置前检查）
+	if value == "" && (key == models.ConfigKeyDingTalkWebhook || key == models.ConfigKeyWeComWebhook) {
+		channelID := models.LegacyDingTalkChannelID
+		if key == models.ConfigKeyWeComWebhook {
+			channelID = models.LegacyWeComChannelID
+		}
+		if err := services.CheckChannelUnused(database.GetDB(), channelID); err != nil {
+			return err
+		}
+	}
+
+	// 日志存储后端校验
+	if key == models.ConfigKeyLogStorage {
+		if err := validateLogStorage(value); err != nil {
+			return err
+		}
+	}
+
+	// 自定义脱敏正则校验
+	if key == models.ConfigKeyRedactPatterns {
+		if err := services.ValidateRedactPatterns(services.ParseRedactRules(value)); err != nil {
+			return err
+		}
+	}
+
 	var config models.Config
 	err := database.GetDB().Where("key = ?", key).First(&config).Error
 
@@ -449,7 +762,7 @@ func (a *App) UpdateConfig(key, value string) error {
 	}
 
 	// 热更新告警配置
-	if key == models.ConfigKeyDingTalkWebhook || key == models.ConfigKeyWeComWebhook {
+	if key == models.ConfigKeyDingTalkWebhook || key == models.ConfigKeyWeComWebhook || key == models.ConfigKeyNotifyDedupMinutes {
 		if err := a.reloadNotifierConfig(); err != nil {
 			return err
 		}
@@ -470,6 +783,26 @@ func (a *App) UpdateConfig(key, value string) error {
 		log.Printf("已热更新执行超时配置: %d 秒", seconds)
 	}
 
+	// 热更新脱敏规则
+	if key == models.ConfigKeyRedactSecrets || key == models.ConfigKeyRedactPatterns || key == models.ConfigKeyRedactBuiltinEnabled {
+		if err := a.reloadRedactorConfig(); err != nil {
+			return err
+		}
+	}
+
+	// 热更新日志存储后端（对之后开始的执行生效）
+	if key == models.ConfigKeyLogStorage {
+		a.executor.SetLogStorage(value)
+		log.Printf("已热更新日志存储配置: %s", value)
+	}
+
+	// 热更新执行汇总的发送时间
+	if key == models.ConfigKeyDigestDailyEnabled || key == models.ConfigKeyDigestWeeklyEnabled || key == models.ConfigKeyDigestTime {
+		if err := a.digest.Reload(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -492,6 +825,117 @@ func (a *App) TestNotification(target string, webhook string) error {
 	return a.notifier.SendTest(target, webhook)
 }
 
+// ==================== 通知渠道 API ====================
+
+// legacyChannelConfigKey 旧版 webhook 渠道对应的配置项（其他渠道返回空）
+func legacyChannelConfigKey(channelID string) string {
+	switch channelID {
+	case models.LegacyDingTalkChannelID:
+		return models.ConfigKeyDingTalkWebhook
+	case models.LegacyWeComChannelID:
+		return models.ConfigKeyWeComWebhook
+	}
+	return ""
+}
+
+// GetChannels 获取全部通知渠道
+func (a *App) GetChannels() ([]models.NotifyChannel, error) {
+	var channels []models.NotifyChannel
+	if err := database.GetDB().Order("created_at").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// GetChannelTypes 获取支持的通知渠道类型
+func (a *App) GetChannelTypes() []string {
+	return services.ChannelTypes()
+}
+
+// CreateChannel 创建通知渠道（按传入的 enabled 保存，可创建停用的渠道）
+func (a *App) CreateChannel(channel models.NotifyChannel) error {
+	channel.ID = ""
+	if err := services.ValidateChannel(&channel); err != nil {
+		return err
+	}
+	if err := database.GetDB().Create(&channel).Error; err != nil {
+		return err
+	}
+	return a.notifier.LoadChannels()
+}
+
+// UpdateChannel 更新通知渠道（类型不可修改，按已保存的类型校验配置）
+func (a *App) UpdateChannel(channel models.NotifyChannel) error {
+	var old models.NotifyChannel
+	if err := database.GetDB().First(&old, "id = ?", channel.ID).Error; err != nil {
+		return err
+	}
+	channel.Type = old.Type
+	channel.CreatedAt = old.CreatedAt
+	if err := services.ValidateChannel(&channel); err != nil {
+		return err
+	}
+	key := legacyChannelConfigKey(channel.ID)
+	if key != "" && strings.TrimSpace(channel.Config["webhook"]) == "" {
+		// 清空系统设置中的 webhook 会删除渠道，更新时不允许
+		return fmt.Errorf("Webhook 不能为空，如需移除请删除该渠道")
+	}
+	if err := database.GetDB().Save(&channel).Error; err != nil {
+		return err
+	}
+	// 旧版 webhook 渠道同时回写系统设置，保持两处一致
+	if key != "" {
+		return a.UpdateConfig(key, channel.Config["webhook"])
+	}
+	return a.notifier.LoadChannels()
+}
+
+// DeleteChannel 删除通知渠道（仍被任务通知规则或执行汇总引用时拒绝删除）
+func (a *App) DeleteChannel(channelID string) error {
+	if key := legacyChannelConfigKey(channelID); key != "" {
+		// 清空系统设置中的 webhook 即删除对应渠道
+		return a.UpdateConfig(key, "")
+	}
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := services.CheckChannelUnused(tx, channelID); err != nil {
+			return err
+		}
+		return tx.Delete(&models.NotifyChannel{}, "id = ?", channelID).Error
+	})
+	if err != nil {
+		return err
+	}
+	return a.notifier.LoadChannels()
+}
+
+// TestChannel 按渠道配置发送测试通知（可用于保存前测试）
+func (a *App) TestChannel(channel models.NotifyChannel) error {
+	if err := services.ValidateChannel(&channel); err != nil {
+		return err
+	}
+	return a.notifier.TestChannel(channel)
+}
+
+// ListNotifications 查询告警发送记录（status 可选 pending/sending/sent/failed，为空查询全部）
+func (a *App) ListNotifications(status string, limit int) ([]models.Notification, error) {
+	return a.notifier.ListNotifications(status, limit)
+}
+
+// RetryNotification 重新发送已失败的告警
+func (a *App) RetryNotification(notificationID string) error {
+	return a.notifier.RetryNotification(notificationID)
+}
+
+// GetDigest 统计执行汇总（period 为 daily / weekly，统计前一天或上一周）
+func (a *App) GetDigest(period string) (*services.Digest, error) {
+	return services.BuildDigest(period, services.NowBeijing())
+}
+
+// SendDigest 立即发送一期执行汇总
+func (a *App) SendDigest(period string) error {
+	return a.digest.Send(period)
+}
+
 // ==================== 开机自启动 API ====================
 
 const autoStartAppName = "ScriptGuard"
@@ -612,24 +1056,25 @@ func (a *App) ExportDebugLogs(frontendLogs string) (string, error) {
 	// 写入前端控制台日志（如果有）
 	if frontendLogs != "" {
 		content.WriteString("--- 前端控制台日志 ---\n")
-		content.WriteString(frontendLogs)
+		content.WriteString(a.redactor.Redact(frontendLogs))
 		content.WriteString("\n\n")
 	}
 
-	// 从数据库获取最近的任务执行日志（最近500条）
-	var logs []models.Log
-	database.GetDB().Order("timestamp DESC").Limit(500).Find(&logs)
+	// 获取最近的任务执行日志（最近500条，合并数据库与文件存储后端）
+	logs, err := services.QueryRecentLogs("", "", 500)
+	if err != nil {
+		content.WriteString(fmt.Sprintf("读取任务执行日志失败: %v\n", err))
+	}
 
 	content.WriteString("--- 任务执行日志（最近500条）---\n")
 	if len(logs) == 0 {
 		content.WriteString("暂无日志\n")
 	} else {
-		for i := len(logs) - 1; i >= 0; i-- { // 倒序输出，最早的在前
-			logEntry := logs[i]
+		for _, logEntry := range logs { // 已按时间正序，最早的在前
 			content.WriteString(fmt.Sprintf("[%s] [%s] %s\n",
 				logEntry.Timestamp.Format("2006-01-02 15:04:05"),
 				strings.ToUpper(string(logEntry.Level)),
-				logEntry.Content,
+				a.redactor.Redact(logEntry.Content), // 兼容脱敏规则配置前已落库的日志
 			))
 		}
 	}
@@ -13,14 +13,23 @@ import (
 
 var DB *gorm.DB
 
-// GetDefaultDBPath 获取默认数据库路径（用户数据目录）
-func GetDefaultDBPath() (string, error) {
+// GetDefaultDataDir 获取应用数据根目录（数据库、日志文件等均位于其下）
+func GetDefaultDataDir() (string, error) {
 	// 使用用户配置目录（Windows: %AppData%, Linux: ~/.config, macOS: ~/Library/Application Support）
 	configDir, err := os.UserConfigDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(configDir, "ScriptGuard", "database", "scriptguard.db"), nil
+	return filepath.Join(configDir, "ScriptGuard"), nil
+}
+
+// GetDefaultDBPath 获取默认数据库路径（用户数据目录）
+func GetDefaultDBPath() (string, error) {
+	dataDir, err := GetDefaultDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "database", "scriptguard.db"), nil
 }
 
 // InitDB 初始化数据库
@@ -60,12 +69,22 @@ func InitDB(dbPath string) error {
 		&models.Task{},
 		&models.Execution{},
 		&models.Log{},
+		&models.LogFile{},
+		&models.Artifact{},
+		&models.Pool{},
+		&models.NotifyChannel{},
+		&models.Notification{},
 		&models.Config{},
 	)
 	if err != nil {
 		return err
 	}
 
+	// 日志全文索引（FTS5 不可用时降级为 LIKE 检索，不影响启动）
+	if err := initLogSearchIndex(); err != nil {
+		log.Printf("初始化日志全文索引失败，日志搜索将降级为逐行匹配: %v", err)
+	}
+
 	// 初始化默认配置
 	if err := initDefaultConfig(); err != nil {
 		log.Printf("初始化默认配置失败: %v", err)
@@ -82,6 +101,16 @@ func initDefaultConfig() error {
 		models.ConfigKeyMaxConcurrency:          "5",
 		models.ConfigKeyExecutionTimeoutSeconds: "3600",
 		models.ConfigKeyAutoStartEnabled:        "false",
+		models.ConfigKeyLogStorage:              models.LogStorageDatabase,
+		models.ConfigKeyRedactSecrets:           "",
+		models.ConfigKeyRedactPatterns:          "",
+		models.ConfigKeyRedactBuiltinEnabled:    "true",
+		models.ConfigKeyArtifactRetentionDays:   "30",
+		models.ConfigKeyNotifyDedupMinutes:      "30",
+		models.ConfigKeyDigestDailyEnabled:      "false",
+		models.ConfigKeyDigestWeeklyEnabled:     "false",
+		models.ConfigKeyDigestTime:              "08:30",
+		models.ConfigKeyDigestChannels:          "",
 	}
 
 	for key, value := range defaults {
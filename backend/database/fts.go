@@ -0,0 +1,89 @@
+package database
+
+// 日志全文索引：基于 FTS5 外部内容表（content='logs'），正文只存一份，由触发器保持同步
+// 使用 trigram 分词，支持中文及任意子串检索（检索词至少 3 个字符）
+// 注意：mattn/go-sqlite3 需以 -tags sqlite_fts5 构建才包含 FTS5
+// 数据库曾被包含 FTS5 的版本打开后又被不含 FTS5 的版本打开时，需删除同步触发器，否则写入 logs 会失败
+
+import "errors"
+
+// LogSearchTable 日志全文索引表名
+const LogSearchTable = "logs_fts"
+
+var logSearchEnabled bool
+
+// LogSearchEnabled 日志全文索引是否可用
+func LogSearchEnabled() bool {
+	return logSearchEnabled
+}
+
+// logSearchTriggers 保持全文索引同步的触发器
+var logSearchTriggers = []string{"logs_fts_ai", "logs_fts_ad", "logs_fts_au"}
+
+// initLogSearchIndex 创建全文索引表与同步触发器
+// 首次创建或触发器曾被删除（期间的日志未进入索引）时为已有日志重建索引；FTS5 不可用时删除触发器并返回错误
+func initLogSearchIndex() error {
+	if err := probeFTS5(); err != nil {
+		return errors.Join(err, dropLogSearchTriggers())
+	}
+
+	var tables, triggers int64
+	if err := DB.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", LogSearchTable).
+		Scan(&tables).Error; err != nil {
+		return err
+	}
+	if err := DB.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ?", logSearchTriggers).
+		Scan(&triggers).Error; err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS logs_fts USING fts5(content, content='logs', content_rowid='rowid', tokenize='trigram')`,
+		`CREATE TRIGGER IF NOT EXISTS logs_fts_ai AFTER INSERT ON logs BEGIN
+			INSERT INTO logs_fts(rowid, content) VALUES (new.rowid, new.content);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS logs_fts_ad AFTER DELETE ON logs BEGIN
+			INSERT INTO logs_fts(logs_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS logs_fts_au AFTER UPDATE ON logs BEGIN
+			INSERT INTO logs_fts(logs_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+			INSERT INTO logs_fts(rowid, content) VALUES (new.rowid, new.content);
+		END`,
+	}
+	for _, stmt := range statements {
+		if err := DB.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+
+	if tables == 0 || int(triggers) < len(logSearchTriggers) {
+		if err := RebuildLogSearchIndex(); err != nil {
+			return err
+		}
+	}
+	logSearchEnabled = true
+	return nil
+}
+
+// probeFTS5 检查当前构建是否包含 FTS5 模块
+func probeFTS5() error {
+	if err := DB.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS temp.fts5_probe USING fts5(content)").Error; err != nil {
+		return err
+	}
+	return DB.Exec("DROP TABLE IF EXISTS temp.fts5_probe").Error
+}
+
+// dropLogSearchTriggers 删除全文索引同步触发器（FTS5 不可用时触发器会使 logs 的写入失败）
+func dropLogSearchTriggers() error {
+	var errs []error
+	for _, name := range logSearchTriggers {
+		errs = append(errs, DB.Exec("DROP TRIGGER IF EXISTS "+name).Error)
+	}
+	return errors.Join(errs...)
+}
+
+// RebuildLogSearchIndex 重建日志全文索引
+// logs 表没有 INTEGER PRIMARY KEY，VACUUM 可能改变 rowid，因此 VACUUM 后需要重建
+func RebuildLogSearchIndex() error {
+	return DB.Exec("INSERT INTO logs_fts(logs_fts) VALUES ('rebuild')").Error
+}
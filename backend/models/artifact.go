@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Artifact 执行产物：执行结束后按任务的产物规则收集并复制到数据目录的文件
+type Artifact struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	ExecutionID string    `json:"execution_id" gorm:"index;not null"`
+	TaskID      string    `json:"task_id" gorm:"index;not null"`
+	Name        string    `json:"name" gorm:"not null"` // 产物文件名（同一执行内唯一）
+	SourcePath  string    `json:"source_path"`          // 原始文件路径
+	Path        string    `json:"path" gorm:"not null"` // 复制后的绝对路径
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+func (a *Artifact) BeforeCreate(_ *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
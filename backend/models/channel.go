@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 内置通知渠道类型
+const (
+	ChannelTypeDingTalk = "dingtalk"
+	ChannelTypeWeCom    = "wecom"
+	ChannelTypeFeishu   = "feishu"
+	ChannelTypeEmail    = "email"
+	ChannelTypeWebhook  = "webhook"
+)
+
+// 由系统设置中的旧版 webhook 配置同步生成的渠道 ID
+const (
+	LegacyDingTalkChannelID = "legacy-dingtalk"
+	LegacyWeComChannelID    = "legacy-wecom"
+)
+
+// NotifyChannel 通知渠道：告警会发送到全部已启用的渠道
+type NotifyChannel struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Type      string    `json:"type" gorm:"not null"` // 渠道类型，决定 Config 的含义
+	Name      string    `json:"name" gorm:"not null"`
+	Config    StringMap `json:"config" gorm:"type:text"` // 渠道配置（如 webhook 地址、签名密钥）
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (c *NotifyChannel) BeforeCreate(_ *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -22,4 +22,14 @@ const (
 	ConfigKeyCloseToTray             = "close_to_tray"             // 关闭时最小化到托盘
 	ConfigKeyTrayHintShown           = "tray_hint_shown"           // 是否已显示托盘提示
 	ConfigKeyAutoStartEnabled        = "auto_start_enabled"        // 是否开机自启动
+	ConfigKeyLogStorage              = "log_storage"               // 日志存储后端：database / file
+	ConfigKeyRedactSecrets           = "redact_secrets"            // 需脱敏的密钥字面量（每行一个）
+	ConfigKeyRedactPatterns          = "redact_patterns"           // 自定义脱敏正则（每行一条，secret 命名组只替换该组）
+	ConfigKeyRedactBuiltinEnabled    = "redact_builtin_enabled"    // 是否启用内置脱敏规则
+	ConfigKeyArtifactRetentionDays   = "artifact_retention_days"   // 执行产物保留天数
+	ConfigKeyNotifyDedupMinutes      = "notify_dedup_minutes"      // 相同告警的去重窗口（分钟，0 表示不去重）
+	ConfigKeyDigestDailyEnabled      = "digest_daily_enabled"      // 是否每天发送前一天的执行汇总
+	ConfigKeyDigestWeeklyEnabled     = "digest_weekly_enabled"     // 是否每周一发送上一周的执行汇总
+	ConfigKeyDigestTime              = "digest_time"               // 汇总发送时间（HH:MM，北京时间）
+	ConfigKeyDigestChannels          = "digest_channels"           // 汇总发送的渠道 ID（逗号分隔，空表示全部渠道）
 )
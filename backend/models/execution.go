@@ -13,6 +13,7 @@ const (
 	StatusRunning ExecutionStatus = "running"
 	StatusSuccess ExecutionStatus = "success"
 	StatusFailed  ExecutionStatus = "failed"
+	StatusWarning ExecutionStatus = "warning" // 进程正常结束，但命中了任务的警告规则
 )
 
 type Execution struct {
@@ -24,6 +25,42 @@ type Execution struct {
 	DurationMs   int64           `json:"duration_ms"`
 	ExitCode     int             `json:"exit_code"`
 	ErrorMessage string          `json:"error_message"`
+	StalledAt    *time.Time      `json:"stalled_at"` // 检测到长时间无输出的时间（未卡住为 null）
+	TimedOut     bool            `json:"timed_out"`  // 是否因执行超时被终止
+
+	// 脚本通过 "::sg" 指令上报的进度、指标与输出值
+	Progress        *float64 `json:"progress"` // 最近上报的进度百分比（未上报为 null）
+	ProgressCurrent float64  `json:"progress_current"`
+	ProgressTotal   float64  `json:"progress_total"`
+	ProgressMessage string   `json:"progress_message"`
+	Metrics         JSONMap  `json:"metrics" gorm:"type:text"`
+	Outputs         JSONMap  `json:"outputs" gorm:"type:text"` // 已脱敏，用于展示
+	RawOutputs      JSONMap  `json:"-" gorm:"type:text"`       // 未脱敏的输出值，仅供下游任务引用，不返回前端
+
+	// 进程树资源占用（执行期间定时采样）
+	CPUTimeMs       int64   `json:"cpu_time_ms"`       // 用户态 + 内核态 CPU 时间
+	CPUPercentAvg   float64 `json:"cpu_percent_avg"`   // 平均 CPU 占用（100 表示占满一个核）
+	CPUPercentPeak  float64 `json:"cpu_percent_peak"`  // 采样间隔内的最高 CPU 占用
+	MemoryPeakBytes int64   `json:"memory_peak_bytes"` // 内存峰值
+	MemoryAvgBytes  int64   `json:"memory_avg_bytes"`  // 内存均值
+	IOReadBytes     int64   `json:"io_read_bytes"`
+	IOWriteBytes    int64   `json:"io_write_bytes"`
+	ResourceSamples int     `json:"resource_samples"` // 采样次数（0 表示当前系统不支持采样）
+}
+
+// ResourcePoint 资源趋势中的一次执行
+type ResourcePoint struct {
+	ExecutionID     string          `json:"execution_id" gorm:"column:id"`
+	StartTime       time.Time       `json:"start_time"`
+	Status          ExecutionStatus `json:"status"`
+	DurationMs      int64           `json:"duration_ms"`
+	CPUTimeMs       int64           `json:"cpu_time_ms"`
+	CPUPercentAvg   float64         `json:"cpu_percent_avg"`
+	CPUPercentPeak  float64         `json:"cpu_percent_peak"`
+	MemoryPeakBytes int64           `json:"memory_peak_bytes"`
+	MemoryAvgBytes  int64           `json:"memory_avg_bytes"`
+	IOReadBytes     int64           `json:"io_read_bytes"`
+	IOWriteBytes    int64           `json:"io_write_bytes"`
 }
 
 func (e *Execution) BeforeCreate(_ *gorm.DB) error {
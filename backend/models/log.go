@@ -10,6 +10,7 @@ import (
 type LogLevel string
 
 const (
+	LogLevelDebug   LogLevel = "debug"
 	LogLevelInfo    LogLevel = "info"
 	LogLevelStdout  LogLevel = "stdout"
 	LogLevelStderr  LogLevel = "stderr"
@@ -25,6 +26,8 @@ type Log struct {
 	Timestamp   time.Time `json:"timestamp" gorm:"index"`
 	Level       LogLevel  `json:"level" gorm:"not null"`
 	Content     string    `json:"content" gorm:"type:text"`
+	Stream      LogLevel  `json:"stream"`                  // 来源输出流：stdout / stderr（系统日志为空）
+	Fields      JSONMap   `json:"fields" gorm:"type:text"` // 结构化日志的其余字段
 }
 
 func (l *Log) BeforeCreate(_ *gorm.DB) error {
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 日志存储后端
+const (
+	LogStorageDatabase = "database" // 每行日志一条 logs 记录（默认）
+	LogStorageFile     = "file"     // 日志正文压缩写入数据目录，数据库仅保存元数据
+)
+
+// LogChunk 日志文件分块索引：每块是一个独立的 gzip member，可直接 Seek 到 Offset 解压
+type LogChunk struct {
+	Offset int64     `json:"offset"` // 压缩文件内的起始偏移
+	Size   int64     `json:"size"`   // 压缩后字节数
+	Line   int       `json:"line"`   // 块内首行在整个文件中的序号（从 0 开始）
+	Count  int       `json:"count"`  // 块内行数
+	Start  time.Time `json:"start"`  // 块内最早日志时间
+	End    time.Time `json:"end"`    // 块内最晚日志时间
+}
+
+// LogChunkList 用于存储分块索引的 JSON 数组
+type LogChunkList []LogChunk
+
+func (l LogChunkList) Value() (driver.Value, error) {
+	data, err := json.Marshal([]LogChunk(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (l *LogChunkList) Scan(value any) error {
+	if value == nil {
+		*l = LogChunkList{}
+		return nil
+	}
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("LogChunkList.Scan: unsupported type %T", value)
+	}
+	if len(raw) == 0 {
+		*l = LogChunkList{}
+		return nil
+	}
+	var items []LogChunk
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return err
+	}
+	*l = LogChunkList(items)
+	return nil
+}
+
+// LogFile 文件存储后端下单次执行的日志元数据
+type LogFile struct {
+	ExecutionID string       `json:"execution_id" gorm:"primaryKey"`
+	TaskID      string       `json:"task_id" gorm:"not null;index"`
+	Path        string       `json:"path" gorm:"not null"` // 压缩日志文件绝对路径
+	Lines       int          `json:"lines"`
+	RawBytes    int64        `json:"raw_bytes"`    // 未压缩字节数
+	StoredBytes int64        `json:"stored_bytes"` // 压缩后字节数
+	FirstTime   time.Time    `json:"first_time"`
+	LastTime    time.Time    `json:"last_time" gorm:"index"`
+	Chunks      LogChunkList `json:"-" gorm:"type:TEXT"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
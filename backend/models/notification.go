@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 告警投递状态
+const (
+	NotificationPending = "pending" // 等待发送（含等待重试）
+	NotificationSending = "sending" // 已交给渠道发送队列
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed" // 重试次数用尽或渠道已不存在
+)
+
+// Notification 告警发件箱：每条告警按渠道先落库，再由后台投递并记录结果
+type Notification struct {
+	ID            string     `json:"id" gorm:"primaryKey"`
+	ChannelID     string     `json:"channel_id" gorm:"index"`
+	ChannelName   string     `json:"channel_name"`
+	TaskID        string     `json:"task_id" gorm:"index"`
+	Event         string     `json:"event"`
+	Title         string     `json:"title"`
+	Payload       string     `json:"-" gorm:"type:text"` // 已脱敏的通知内容（JSON，不含任务配置）
+	Status        string     `json:"status" gorm:"index;not null"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastError     string     `json:"last_error"`
+	Response      string     `json:"response"` // 最近一次投递的响应（截断）
+	CreatedAt     time.Time  `json:"created_at" gorm:"index"`
+	SentAt        *time.Time `json:"sent_at"`
+}
+
+func (n *Notification) BeforeCreate(_ *gorm.DB) error {
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// 通知事件
+const (
+	NotifyEventFailure      = "failure"       // 执行失败（每次）
+	NotifyEventFirstFailure = "first_failure" // 上次成功（或首次执行）后的第一次失败
+	NotifyEventRecovery     = "recovery"      // 失败后恢复成功
+	NotifyEventSuccess      = "success"       // 执行成功（每次）
+	NotifyEventWarning      = "warning"       // 执行结束但命中警告规则
+	NotifyEventTimeout      = "timeout"       // 执行超时被终止
+	NotifyEventSkipped      = "skipped"       // 定时触发因并发限制被跳过
+	NotifyEventLongRunning  = "long_running"  // 运行时间超过阈值（执行期间触发）
+	NotifyEventStall        = "stall"         // 长时间无输出
+)
+
+// NotifyEvents 全部通知事件
+var NotifyEvents = []string{
+	NotifyEventFailure, NotifyEventFirstFailure, NotifyEventRecovery, NotifyEventSuccess, NotifyEventWarning,
+	NotifyEventTimeout, NotifyEventSkipped, NotifyEventLongRunning, NotifyEventStall,
+}
+
+// NotifyRule 任务的一条通知规则
+type NotifyRule struct {
+	Event            string   `json:"event"`
+	Template         string   `json:"template"`          // 消息正文模板（Go text/template），空表示使用默认内容
+	Channels         []string `json:"channels"`          // 发送到的渠道 ID，空表示全部已启用渠道
+	ThresholdMinutes int      `json:"threshold_minutes"` // long_running 事件的运行时长阈值
+}
+
+// NotifyRules 任务的通知规则
+// 未配置时沿用 NotifyOnFailure：失败与卡住时通知全部渠道
+type NotifyRules []NotifyRule
+
+func (r NotifyRules) MarshalJSON() ([]byte, error) {
+	if r == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]NotifyRule(r))
+}
+
+func (r NotifyRules) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal([]NotifyRule(r))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (r *NotifyRules) Scan(value any) error {
+	*r = nil
+	if value == nil {
+		return nil
+	}
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("NotifyRules.Scan: unsupported type %T", value)
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, (*[]NotifyRule)(r))
+}
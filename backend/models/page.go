@@ -0,0 +1,45 @@
+package models
+
+// ExecutionQuery 执行历史分页查询条件
+// Before/After 为上一页返回的游标，二者至多指定一个；均为空时返回最新一页
+type ExecutionQuery struct {
+	TaskID   string            `json:"task_id"`
+	Statuses []ExecutionStatus `json:"statuses"`
+	TimeRange
+	Before string `json:"before"` // 获取比游标更早的记录
+	After  string `json:"after"`  // 获取比游标更新的记录
+	Limit  int    `json:"limit"`  // 每页条数，默认 100，最大 1000
+}
+
+// ExecutionPage 执行历史分页结果（Items 按开始时间倒序）
+type ExecutionPage struct {
+	Items        []Execution `json:"items"`
+	Total        int64       `json:"total"`         // 满足筛选条件的总数（不受游标影响）
+	BeforeCursor string      `json:"before_cursor"` // 本页最早一条的游标，配合 Before 翻到更早一页
+	AfterCursor  string      `json:"after_cursor"`  // 本页最新一条的游标，配合 After 翻到更新一页
+	HasBefore    bool        `json:"has_before"`
+	HasAfter     bool        `json:"has_after"`
+}
+
+// LogQuery 日志分页查询条件（ExecutionID 优先于 TaskID，均为空时查询全部日志）
+// Before/After 为上一页返回的游标，二者至多指定一个；均为空时返回最新一页
+type LogQuery struct {
+	ExecutionID string            `json:"execution_id"`
+	TaskID      string            `json:"task_id"`
+	Levels      []LogLevel        `json:"levels"`
+	Fields      map[string]string `json:"fields"` // 结构化字段等值筛选
+	TimeRange
+	Before string `json:"before"`
+	After  string `json:"after"`
+	Limit  int    `json:"limit"`
+}
+
+// LogPage 日志分页结果（Items 按时间正序）
+type LogPage struct {
+	Items        []Log  `json:"items"`
+	Total        int64  `json:"total"`
+	BeforeCursor string `json:"before_cursor"` // 本页最早一条的游标
+	AfterCursor  string `json:"after_cursor"`  // 本页最新一条的游标（运行中的执行可据此轮询新日志）
+	HasBefore    bool   `json:"has_before"`
+	HasAfter     bool   `json:"has_after"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Pool 并发资源池：引用同一资源池的任务同时运行的数量不超过 MaxRunning（为 1 即互斥组）
+// 资源池与全局最大并发数同时生效
+type Pool struct {
+	Name        string    `json:"name" gorm:"primaryKey"`
+	MaxRunning  int       `json:"max_running" gorm:"not null;default:1"`
+	Description string    `json:"description"`
+	Running     int       `json:"running" gorm:"-"` // 当前占用名额（仅查询时填充）
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
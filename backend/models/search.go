@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// TimeRange 时间范围（Start/End 为空表示不限）
+type TimeRange struct {
+	Start *time.Time `json:"start"`
+	End   *time.Time `json:"end"`
+}
+
+// LogSearchHit 日志搜索命中项（附带所属执行的上下文）
+type LogSearchHit struct {
+	Log
+	Highlight          string          `json:"highlight"` // 已做 HTML 转义，命中片段以 <mark> 包裹
+	TaskName           string          `json:"task_name"`
+	ExecutionStatus    ExecutionStatus `json:"execution_status"`
+	ExecutionStartTime *time.Time      `json:"execution_start_time"`
+	ExecutionExitCode  *int            `json:"execution_exit_code"`
+}
+
+// LogSearchResult 日志搜索结果（NextCursor 为空表示没有更多）
+// 全文搜索只覆盖数据库存储的日志，SkippedFileExecutions 为满足筛选条件、但日志使用文件存储而未被检索的执行数
+type LogSearchResult struct {
+	Hits                  []LogSearchHit `json:"hits"`
+	NextCursor            string         `json:"next_cursor"`
+	SkippedFileExecutions int64          `json:"skipped_file_executions"`
+}
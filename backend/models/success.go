@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// 成功规则命中后的结果级别
+const (
+	RuleSeverityFailed  = "failed"  // 判定为失败（默认）
+	RuleSeverityWarning = "warning" // 判定为警告
+)
+
+// OutputRule 输出匹配规则
+type OutputRule struct {
+	Pattern   string `json:"pattern"`    // 正则表达式
+	Stream    string `json:"stream"`     // stdout / stderr，空表示两者
+	MustMatch bool   `json:"must_match"` // true: 必须出现；false: 不得出现
+	Severity  string `json:"severity"`   // 不满足时的结果：failed / warning
+}
+
+// SuccessRules 任务的成功判定规则（未配置时仅按退出码 0 判定）
+type SuccessRules struct {
+	ExitCodes     []int        `json:"exit_codes"`     // 视为成功的退出码，空表示仅 0
+	OutputRules   []OutputRule `json:"output_rules"`   // 输出匹配规则
+	RequiredFiles []string     `json:"required_files"` // 必须在本次执行开始后生成或更新的文件（glob，相对路径以脚本所在目录为准）
+}
+
+// IsEmpty 是否未配置任何规则
+func (r SuccessRules) IsEmpty() bool {
+	return len(r.ExitCodes) == 0 && len(r.OutputRules) == 0 && len(r.RequiredFiles) == 0
+}
+
+func (r SuccessRules) Value() (driver.Value, error) {
+	if r.IsEmpty() {
+		return nil, nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (r *SuccessRules) Scan(value any) error {
+	*r = SuccessRules{}
+	if value == nil {
+		return nil
+	}
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("SuccessRules.Scan: unsupported type %T", value)
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, r)
+}
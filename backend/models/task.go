@@ -11,6 +11,19 @@ import (
 	"gorm.io/gorm"
 )
 
+// 脚本输出格式（决定是否将输出行解析为结构化日志）
+const (
+	LogFormatAuto   = "auto"   // 自动识别 JSON 行（默认）
+	LogFormatLogfmt = "logfmt" // 识别 JSON 行与 logfmt 行
+	LogFormatPlain  = "plain"  // 不解析，按原文存储
+)
+
+// 无输出卡住时的处理方式
+const (
+	StallActionNotify = "notify" // 仅标记并告警（默认）
+	StallActionKill   = "kill"   // 标记、告警并终止进程
+)
+
 // CronExprList 用于存储多个 Cron 表达式的 JSON 数组
 type CronExprList []string
 
@@ -56,16 +69,37 @@ func (l *CronExprList) Scan(value any) error {
 }
 
 type Task struct {
-	ID              string       `json:"id" gorm:"primaryKey"`
-	Name            string       `json:"name" gorm:"not null"`
-	ScriptPath      string       `json:"script_path" gorm:"not null"`
-	CondaEnv        string       `json:"conda_env" gorm:"not null"`
-	CronExpr        string       `json:"cron_expr" gorm:"not null"`       // 兼容字段：第一条 cron 表达式
-	CronExprs       CronExprList `json:"cron_exprs" gorm:"type:TEXT"`     // 多时间点：JSON 数组
-	Enabled         bool         `json:"enabled" gorm:"default:true"`
-	NotifyOnFailure bool         `json:"notify_on_failure" gorm:"default:true"`
-	CreatedAt       time.Time    `json:"created_at"`
-	UpdatedAt       time.Time    `json:"updated_at"`
+	ID               string       `json:"id" gorm:"primaryKey"`
+	Name             string       `json:"name" gorm:"not null"`
+	ScriptPath       string       `json:"script_path" gorm:"not null"`
+	CondaEnv         string       `json:"conda_env" gorm:"not null"`
+	CronExpr         string       `json:"cron_expr" gorm:"not null"`   // 兼容字段：第一条 cron 表达式
+	CronExprs        CronExprList `json:"cron_exprs" gorm:"type:TEXT"` // 多时间点：JSON 数组
+	Enabled          bool         `json:"enabled" gorm:"default:true"`
+	NotifyOnFailure  bool         `json:"notify_on_failure" gorm:"default:true"`
+	NotifyRules      NotifyRules  `json:"notify_rules" gorm:"type:text"`      // 按事件的通知规则（配置后取代 NotifyOnFailure）
+	LogFormat        string       `json:"log_format" gorm:"default:auto"`     // 输出格式：auto / logfmt / plain
+	Args             string       `json:"args"`                               // 脚本参数（支持模板，可引用上游任务输出）
+	Env              StringMap    `json:"env" gorm:"type:text"`               // 额外环境变量（值支持模板）
+	ArtifactPatterns StringList   `json:"artifact_patterns" gorm:"type:text"` // 产物文件 glob 规则（相对路径以脚本所在目录为准）
+	SuccessRules     SuccessRules `json:"success_rules" gorm:"type:text"`     // 成功判定规则（退出码、输出匹配、产出文件）
+	StallMinutes     int          `json:"stall_minutes"`                      // 连续无输出超过该分钟数视为卡住，0 表示不检测
+	StallAction      string       `json:"stall_action" gorm:"default:notify"` // 卡住时的处理：notify / kill
+
+	// 资源限制（0 表示不限制）
+	MaxMemoryMB     int `json:"max_memory_mb"`     // 进程树内存上限，超出时终止
+	CPULimitPercent int `json:"cpu_limit_percent"` // CPU 占用上限（占整机的百分比 1~100）
+	MaxProcesses    int `json:"max_processes"`     // 进程数上限（包含 cmd、conda 自身）
+	MaxOpenFiles    int `json:"max_open_files"`    // 打开文件数上限（仅 Linux）
+	NiceLevel       int `json:"nice_level"`        // 调度优先级 -20~19，越大越低（Windows 映射为优先级类）
+
+	// 并发排队
+	Priority int        `json:"priority"`                // 排队优先级，越大越先获得并发槽位
+	Weight   int        `json:"weight" gorm:"default:1"` // 每次执行占用的并发槽位数
+	Pools    StringList `json:"pools" gorm:"type:text"`  // 所属资源池，需同时获得全部资源池的名额才能运行
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // NormalizeCron 归一化 cron 表达式，确保 CronExprs 和 CronExpr 一致
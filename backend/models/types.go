@@ -0,0 +1,134 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONMap 用于存储任意键值对的 JSON 对象（空值存为 NULL）
+type JSONMap map[string]any
+
+func (m JSONMap) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]any(m))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (m *JSONMap) Scan(value any) error {
+	if value == nil {
+		*m = nil
+		return nil
+	}
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("JSONMap.Scan: unsupported type %T", value)
+	}
+	if len(raw) == 0 {
+		*m = nil
+		return nil
+	}
+	var items map[string]any
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return err
+	}
+	*m = JSONMap(items)
+	return nil
+}
+
+// StringMap 用于存储字符串键值对的 JSON 对象（空值存为 NULL）
+type StringMap map[string]string
+
+func (m StringMap) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]string(m))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (m *StringMap) Scan(value any) error {
+	if value == nil {
+		*m = nil
+		return nil
+	}
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("StringMap.Scan: unsupported type %T", value)
+	}
+	if len(raw) == 0 {
+		*m = nil
+		return nil
+	}
+	var items map[string]string
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return err
+	}
+	*m = StringMap(items)
+	return nil
+}
+
+// StringList 用于存储字符串列表的 JSON 数组（空值存为 NULL）
+type StringList []string
+
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(l))
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (l *StringList) Scan(value any) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("StringList.Scan: unsupported type %T", value)
+	}
+	if len(raw) == 0 {
+		*l = nil
+		return nil
+	}
+	var items []string
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return err
+	}
+	*l = StringList(items)
+	return nil
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"scriptguard/backend/database"
+	"scriptguard/backend/models"
+	"strings"
+	"time"
+)
+
+// 产物收集限制
+const (
+	maxArtifactsPerExecution = 100             // 单次执行最多收集的文件数
+	artifactMtimeTolerance   = 2 * time.Second // 文件系统时间精度容差（FAT 等为 2 秒）
+)
+
+// ValidateArtifactPatterns 校验产物 glob 规则
+func ValidateArtifactPatterns(patterns []string) error {
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			return fmt.Errorf("产物规则不能为空")
+		}
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("产物规则非法(%s): %w", p, err)
+		}
+	}
+	return nil
+}
+
+// SetArtifactDir 设置执行产物根目录
+func (s *ExecutorService) SetArtifactDir(dir string) {
+	s.artifactDir = dir
+}
+
+// captureArtifacts 按任务的产物规则收集本次执行期间生成或修改的文件，复制到产物目录并记录
+// 相对路径以脚本所在目录为基准；执行开始前就已存在且未修改的文件不收集
+func (s *ExecutorService) captureArtifacts(task *models.Task, execution *models.Execution) ([]models.Artifact, error) {
+	if len(task.ArtifactPatterns) == 0 {
+		return nil, nil
+	}
+
+	since := execution.StartTime.Add(-artifactMtimeTolerance)
+
+	var sources []string
+	seen := make(map[string]struct{})
+	for _, pattern := range task.ArtifactPatterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		pattern = resolveTaskPath(task, pattern)
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("产物规则非法(%s): %w", pattern, err)
+		}
+		for _, match := range matches {
+			if _, ok := seen[match]; ok {
+				continue
+			}
+			info, err := os.Stat(match)
+			if err != nil || !info.Mode().IsRegular() || info.ModTime().Before(since) {
+				continue
+			}
+			seen[match] = struct{}{}
+			sources = append(sources, match)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	var skipped int
+	if len(sources) > maxArtifactsPerExecution {
+		skipped = len(sources) - maxArtifactsPerExecution
+		sources = sources[:maxArtifactsPerExecution]
+	}
+
+	destDir := filepath.Join(s.artifactDir, execution.StartTime.Format("200601"), execution.ID)
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建产物目录失败: %w", err)
+	}
+
+	artifacts := make([]models.Artifact, 0, len(sources))
+	names := make(map[string]struct{}, len(sources))
+	for _, src := range sources {
+		name := uniqueArtifactName(filepath.Base(src), names)
+		dest := filepath.Join(destDir, name)
+		size, sum, err := copyArtifact(src, dest)
+		if err != nil {
+			log.Printf("复制产物失败(execution_id=%s, path=%s): %v", execution.ID, src, err)
+			continue
+		}
+		artifacts = append(artifacts, models.Artifact{
+			ExecutionID: execution.ID,
+			TaskID:      task.ID,
+			Name:        name,
+			SourcePath:  src,
+			Path:        dest,
+			Size:        size,
+			SHA256:      sum,
+			CreatedAt:   NowBeijing(),
+		})
+	}
+	if len(artifacts) > 0 {
+		if err := database.GetDB().Create(&artifacts).Error; err != nil {
+			return nil, fmt.Errorf("保存产物记录失败: %w", err)
+		}
+	}
+	if skipped > 0 {
+		return artifacts, fmt.Errorf("匹配的文件超过 %d 个，已忽略 %d 个", maxArtifactsPerExecution, skipped)
+	}
+	return artifacts, nil
+}
+
+// resolveTaskPath 相对路径以脚本所在目录为基准
+func resolveTaskPath(task *models.Task, path string) string {
+	path = strings.TrimSpace(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(task.ScriptPath), path)
+}
+
+// uniqueArtifactName 同名文件追加序号：report.csv → report (2).csv
+func uniqueArtifactName(name string, used map[string]struct{}) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 2; ; i++ {
+		if _, ok := used[strings.ToLower(candidate)]; !ok {
+			break
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", stem, i, ext)
+	}
+	used[strings.ToLower(candidate)] = struct{}{}
+	return candidate
+}
+
+// copyArtifact 复制文件并计算 sha256
+func copyArtifact(src, dest string) (int64, string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, "", err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return 0, "", err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest)
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// RemoveArtifactsBefore 删除早于 cutoff 的执行产物文件及记录
+func RemoveArtifactsBefore(cutoff time.Time) (int, error) {
+	db := database.GetDB()
+
+	var artifacts []models.Artifact
+	if err := db.Where("created_at < ?", cutoff).Find(&artifacts).Error; err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	dirs := make(map[string]struct{})
+	for _, artifact := range artifacts {
+		if err := os.Remove(artifact.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("删除产物文件失败(path=%s): %v", artifact.Path, err)
+			continue
+		}
+		if err := db.Delete(&models.Artifact{}, "id = ?", artifact.ID).Error; err != nil {
+			log.Printf("删除产物记录失败(id=%s): %v", artifact.ID, err)
+			continue
+		}
+		dirs[filepath.Dir(artifact.Path)] = struct{}{}
+		removed++
+	}
+	// 删除已清空的执行产物目录（非空时 Remove 会失败，忽略即可）
+	for dir := range dirs {
+		_ = os.Remove(dir)
+	}
+	return removed, nil
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"scriptguard/backend/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NotificationField 通知中的一项键值信息
+type NotificationField struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// 通知级别（渠道可据此选择卡片颜色等）
+const (
+	NotificationLevelError   = "error"
+	NotificationLevelWarning = "warning"
+	NotificationLevelInfo    = "info"
+)
+
+// 日志摘录限制
+const (
+	notifyExcerptLines    = 10
+	notifyExcerptLineSize = 200 // 单行最多字符数
+)
+
+// Notification 渠道无关的通知内容，由各渠道自行渲染为文本或卡片
+// 序列化后写入告警发件箱，由后台投递时还原
+type Notification struct {
+	Title   string              `json:"title"` // 标题（含表情前缀，如"⚠️ 脚本执行失败"）
+	Event   string              `json:"event,omitempty"`
+	Level   string              `json:"level"`
+	Fields  []NotificationField `json:"fields,omitempty"`
+	Note    string              `json:"note,omitempty"`    // 附加说明（可选），显示在字段之后
+	Excerpt string              `json:"excerpt,omitempty"` // 最近日志摘录（可选，失败时为 stderr 的最后几行）
+
+	Traceback string `json:"traceback,omitempty"` // 脚本输出的 Python traceback（可选）
+	Markdown  string `json:"markdown,omitempty"`  // Markdown 正文（可选，如执行汇总），不支持的渠道按纯文本显示
+
+	ExecutionID string            `json:"execution_id,omitempty"` // 关联的执行（可选，用于附带完整日志）
+	Task        *models.Task      `json:"-"`                      // 关联的任务（可选，供自定义模板使用；含环境变量等原始配置，不写入发件箱，投递时按任务 ID 重新读取）
+	Execution   *models.Execution `json:"execution,omitempty"`    // 关联的执行记录（可选，供自定义模板使用）
+	redactor    *Redactor         // 渠道自行读取或渲染的内容（如完整日志）按同一规则脱敏
+}
+
+// Text 渲染为纯文本
+func (n *Notification) Text() string {
+	var b strings.Builder
+	b.WriteString(n.Title)
+	if len(n.Fields) > 0 {
+		b.WriteString("\n")
+	}
+	for _, field := range n.Fields {
+		fmt.Fprintf(&b, "\n%s: %s", field.Label, field.Value)
+	}
+	if n.Note != "" {
+		b.WriteString("\n\n")
+		b.WriteString(n.Note)
+	}
+	if n.Markdown != "" {
+		b.WriteString("\n\n")
+		b.WriteString(n.Markdown)
+	}
+	return b.String()
+}
+
+// fullText 渲染为包含日志摘录与 traceback 的纯文本（供只支持文本消息的渠道使用）
+func (n *Notification) fullText() string {
+	var b strings.Builder
+	b.WriteString(n.Text())
+	if n.Excerpt != "" {
+		b.WriteString("\n\n最近日志:\n")
+		b.WriteString(n.Excerpt)
+	}
+	if n.Traceback != "" {
+		b.WriteString("\n\nTraceback:\n")
+		b.WriteString(n.Traceback)
+	}
+	return b.String()
+}
+
+// redacted 返回脱敏后的副本
+func (n *Notification) redacted(redactor *Redactor) *Notification {
+	out := &Notification{
+		Title:   redactor.Redact(n.Title),
+		Event:   n.Event,
+		Level:   n.Level,
+		Fields:  make([]NotificationField, len(n.Fields)),
+		Note:    redactor.Redact(n.Note),
+		Excerpt: redactor.Redact(n.Excerpt),
+
+		Traceback: redactor.Redact(n.Traceback),
+		Markdown:  redactor.Redact(n.Markdown),
+
+		ExecutionID: n.ExecutionID,
+		Task:        n.Task,
+		redactor:    redactor,
+	}
+	for i, field := range n.Fields {
+		out.Fields[i] = NotificationField{Label: field.Label, Value: redactor.Redact(field.Value)}
+	}
+	if n.Execution != nil {
+		execution := *n.Execution
+		execution.ErrorMessage = redactor.Redact(execution.ErrorMessage)
+		execution.ProgressMessage = redactor.Redact(execution.ProgressMessage)
+		out.Execution = &execution
+	}
+	return out
+}
+
+// logExcerpt 读取执行最近的日志作为摘录，读取失败返回空串
+func logExcerpt(executionID string) string {
+	logs, err := QueryRecentLogs(executionID, "", notifyExcerptLines)
+	if err != nil || len(logs) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(logs))
+	for _, entry := range logs {
+		lines = append(lines, truncateRunes(entry.Content, notifyExcerptLineSize))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateRunes 按字符截断字符串
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return s
+}
+
+// formatDurationMs 将毫秒耗时格式化为易读文本
+func formatDurationMs(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	if d < time.Minute {
+		return fmt.Sprintf("%.1f 秒", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
+
+// Channel 通知渠道
+type Channel interface {
+	// Send 发送通知，返回对方的响应摘要（记录到告警发件箱，失败时也尽量返回）
+	Send(n *Notification) (string, error)
+}
+
+// ChannelFactory 根据渠道配置创建渠道，配置不完整时返回错误
+type ChannelFactory func(config map[string]string, client *http.Client) (Channel, error)
+
+var (
+	channelMu        sync.RWMutex
+	channelFactories = make(map[string]ChannelFactory)
+)
+
+// RegisterChannelType 注册通知渠道类型（在各渠道实现的 init 中调用）
+func RegisterChannelType(typ string, factory ChannelFactory) {
+	channelMu.Lock()
+	defer channelMu.Unlock()
+	channelFactories[typ] = factory
+}
+
+// ChannelTypes 已注册的渠道类型
+func ChannelTypes() []string {
+	channelMu.RLock()
+	defer channelMu.RUnlock()
+	types := make([]string, 0, len(channelFactories))
+	for typ := range channelFactories {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// newChannel 按类型创建渠道
+func newChannel(typ string, config map[string]string, client *http.Client) (Channel, error) {
+	channelMu.RLock()
+	factory, ok := channelFactories[typ]
+	channelMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知通知类型: %s", typ)
+	}
+	return factory(config, client)
+}
+
+// ValidateChannel 校验并归一化通知渠道
+func ValidateChannel(channel *models.NotifyChannel) error {
+	channel.Name = strings.TrimSpace(channel.Name)
+	if channel.Name == "" {
+		return fmt.Errorf("渠道名称不能为空")
+	}
+	for key, value := range channel.Config {
+		channel.Config[key] = strings.TrimSpace(value)
+	}
+	_, err := newChannel(channel.Type, channel.Config, http.DefaultClient)
+	return err
+}
+
+// requireConfig 读取必填配置项
+func requireConfig(config map[string]string, key, label string) (string, error) {
+	value := strings.TrimSpace(config[key])
+	if value == "" {
+		return "", fmt.Errorf("%s未配置", label)
+	}
+	return value, nil
+}
+
+// maxWebhookResponseBytes 读取 webhook 响应体的上限
+const maxWebhookResponseBytes = 4096
+
+// postWebhook 发送webhook请求并返回响应体，供需要检查业务错误码的渠道使用
+func postWebhook(client *http.Client, url string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return doWebhook(client, http.MethodPost, url, http.Header{"Content-Type": {"application/json"}}, data)
+}
+
+// doWebhook 发送任意方法与请求头的webhook请求并返回响应体（非2xx视为失败，同时返回响应体）
+func doWebhook(client *http.Client, method, url string, header http.Header, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBytes))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return respBody, fmt.Errorf("webhook返回非2xx: %s, body=%s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	return respBody, nil
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"scriptguard/backend/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterChannelType(models.ChannelTypeDingTalk, newDingTalkChannel)
+}
+
+// dingTalkChannel 钉钉群机器人，发送 markdown 消息
+// 配置项：webhook（必填）、secret（安全设置为"加签"时填写）、keyword（安全设置为"自定义关键词"时填写，
+// 消息不含关键词时自动加在标题前）、at_mobiles（失败时 @ 的手机号，逗号分隔）、at_all（"true" 时失败 @所有人）
+type dingTalkChannel struct {
+	webhook   string
+	secret    string
+	keyword   string
+	atMobiles []string
+	atAll     bool
+	client    *http.Client
+}
+
+func newDingTalkChannel(config map[string]string, client *http.Client) (Channel, error) {
+	webhook, err := requireConfig(config, "webhook", "钉钉 Webhook ")
+	if err != nil {
+		return nil, err
+	}
+	c := &dingTalkChannel{
+		webhook: webhook,
+		secret:  strings.TrimSpace(config["secret"]),
+		keyword: strings.TrimSpace(config["keyword"]),
+		client:  client,
+	}
+	for _, mobile := range strings.FieldsFunc(config["at_mobiles"], isListSeparator) {
+		if mobile = strings.TrimSpace(mobile); mobile != "" {
+			c.atMobiles = append(c.atMobiles, mobile)
+		}
+	}
+	if v := strings.TrimSpace(config["at_all"]); v != "" {
+		if c.atAll, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("at_all 只能为 true 或 false")
+		}
+	}
+	return c, nil
+}
+
+// isListSeparator 列表配置的分隔符（逗号、中文逗号、分号、空白）
+func isListSeparator(r rune) bool {
+	switch r {
+	case ',', '，', ';', '；', ' ', '\t', '\n':
+		return true
+	}
+	return false
+}
+
+// dingTalkSign 计算加签：以 secret 为密钥对 timestamp + "\n" + secret 做 HmacSHA256 后 Base64
+func dingTalkSign(timestampMs int64, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d\n%s", timestampMs, secret)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signedURL 在 webhook 上附加 timestamp 与 sign 参数
+func (c *dingTalkChannel) signedURL() (string, error) {
+	if c.secret == "" {
+		return c.webhook, nil
+	}
+	u, err := url.Parse(c.webhook)
+	if err != nil {
+		return "", fmt.Errorf("钉钉 Webhook 地址非法: %w", err)
+	}
+	timestamp := time.Now().UnixMilli()
+	query := u.Query()
+	query.Set("timestamp", strconv.FormatInt(timestamp, 10))
+	query.Set("sign", dingTalkSign(timestamp, c.secret))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// markdown 渲染 markdown 正文（钉钉 markdown 中换行需要空行或列表）
+func (c *dingTalkChannel) markdown(n *Notification, title string, mention bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n\n", title)
+	for _, field := range n.Fields {
+		fmt.Fprintf(&b, "- **%s**: %s\n", field.Label, field.Value)
+	}
+	if n.Excerpt != "" {
+		b.WriteString("\n**最近日志**\n\n")
+		for _, line := range strings.Split(n.Excerpt, "\n") {
+			fmt.Fprintf(&b, "> %s\n>\n", line)
+		}
+	}
+	if n.Traceback != "" {
+		fmt.Fprintf(&b, "\n**Traceback**\n\n```\n%s\n```\n", n.Traceback)
+	}
+	if n.Note != "" {
+		fmt.Fprintf(&b, "\n%s\n", n.Note)
+	}
+	if n.Markdown != "" {
+		fmt.Fprintf(&b, "\n%s\n", n.Markdown)
+	}
+	if mention {
+		// 钉钉要求正文中包含 @手机号 才会高亮提醒
+		var mentions []string
+		for _, mobile := range c.atMobiles {
+			mentions = append(mentions, "@"+mobile)
+		}
+		if c.atAll {
+			mentions = append(mentions, "@所有人")
+		}
+		if len(mentions) > 0 {
+			fmt.Fprintf(&b, "\n%s\n", strings.Join(mentions, " "))
+		}
+	}
+	return b.String()
+}
+
+// dingTalkMaxBytes markdown 消息上限 20000 字节（预留格式开销）
+const dingTalkMaxBytes = 18000
+
+func (c *dingTalkChannel) Send(n *Notification) (string, error) {
+	n = fitNotification(n, dingTalkMaxBytes)
+	// 仅失败类通知 @ 相关人员
+	mention := n.Level == NotificationLevelError
+	title := n.Title
+	text := c.markdown(n, title, mention)
+	if c.keyword != "" && !strings.Contains(text, c.keyword) {
+		title = fmt.Sprintf("[%s] %s", c.keyword, title)
+		text = c.markdown(n, title, mention)
+	}
+
+	at := map[string]any{}
+	if mention {
+		at["atMobiles"] = c.atMobiles
+		at["isAtAll"] = c.atAll
+	}
+	payload := map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": title,
+			"text":  text,
+		},
+		"at": at,
+	}
+
+	target, err := c.signedURL()
+	if err != nil {
+		return "", err
+	}
+	body, err := postWebhook(c.client, target, payload)
+	if err != nil {
+		return string(body), err
+	}
+	// 钉钉在 HTTP 200 中通过 errcode 返回业务错误（如加签校验失败、缺少关键词）
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if json.Unmarshal(body, &result) == nil && result.ErrCode != 0 {
+		return string(body), fmt.Errorf("钉钉返回错误: errcode=%d, errmsg=%s", result.ErrCode, result.ErrMsg)
+	}
+	return string(body), nil
+}
+
+// RateLimit 机器人每分钟最多接收 20 条消息
+func (c *dingTalkChannel) RateLimit() (int, time.Duration) {
+	return 20, time.Minute
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"scriptguard/backend/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterChannelType(models.ChannelTypeEmail, newEmailChannel)
+}
+
+// SMTP 连接加密方式
+const (
+	smtpSecurityStartTLS = "starttls" // 明文连接后升级（通常为 587 端口，默认）
+	smtpSecurityTLS      = "tls"      // 隐式 TLS（通常为 465 端口）
+	smtpSecurityNone     = "none"     // 不加密（仅用于本地或内网中继）
+)
+
+// 邮件发送限制
+const (
+	smtpTimeout           = 30 * time.Second
+	maxEmailLogLines      = 50000 // 附件最多包含的日志行数
+	emailBase64LineLength = 76
+	emailMaxBytes         = 64 * 1024 // 正文中的摘录上限（完整日志见附件）
+)
+
+// emailChannel SMTP 邮件
+// 配置项：host、port、from、to（多个收件人以逗号分隔）为必填；username、password 用于认证；
+// security 为 starttls / tls / none；attach_log 为 "true" 时附带执行的完整日志
+type emailChannel struct {
+	host      string
+	port      string
+	username  string
+	password  string
+	from      *mail.Address
+	to        []string
+	security  string
+	attachLog bool
+}
+
+func newEmailChannel(config map[string]string, _ *http.Client) (Channel, error) {
+	host, err := requireConfig(config, "host", "SMTP 服务器")
+	if err != nil {
+		return nil, err
+	}
+	c := &emailChannel{
+		host:     host,
+		port:     strings.TrimSpace(config["port"]),
+		username: strings.TrimSpace(config["username"]),
+		password: config["password"],
+		security: strings.ToLower(strings.TrimSpace(config["security"])),
+	}
+	switch c.security {
+	case "":
+		c.security = smtpSecurityStartTLS
+		if c.port == "465" {
+			c.security = smtpSecurityTLS
+		}
+	case smtpSecurityStartTLS, smtpSecurityTLS, smtpSecurityNone:
+	default:
+		return nil, fmt.Errorf("未知的 SMTP 加密方式: %s", c.security)
+	}
+	if c.port == "" {
+		c.port = "587"
+		if c.security == smtpSecurityTLS {
+			c.port = "465"
+		}
+	}
+	if port, err := strconv.Atoi(c.port); err != nil || port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("SMTP 端口非法: %s", c.port)
+	}
+	// net/smtp 的 PLAIN 认证拒绝在未加密连接上发送密码（本机除外）
+	if c.security == smtpSecurityNone && c.username != "" && !isLocalSMTPHost(c.host) {
+		return nil, fmt.Errorf("不加密的连接仅支持本机 SMTP 服务器认证，请改用 starttls 或 tls，或清空用户名")
+	}
+
+	from, err := requireConfig(config, "from", "发件人")
+	if err != nil {
+		return nil, err
+	}
+	if c.from, err = mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("发件人地址非法: %w", err)
+	}
+	for _, item := range strings.FieldsFunc(config["to"], isListSeparator) {
+		addr, err := mail.ParseAddress(item)
+		if err != nil {
+			return nil, fmt.Errorf("收件人地址非法(%s): %w", item, err)
+		}
+		c.to = append(c.to, addr.Address)
+	}
+	if len(c.to) == 0 {
+		return nil, fmt.Errorf("收件人未配置")
+	}
+	if v := strings.TrimSpace(config["attach_log"]); v != "" {
+		if c.attachLog, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("attach_log 只能为 true 或 false")
+		}
+	}
+	return c, nil
+}
+
+// isLocalSMTPHost 是否为本机地址（与 smtp.PlainAuth 允许明文认证的范围一致）
+func isLocalSMTPHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+func (c *emailChannel) Send(n *Notification) (string, error) {
+	if err := c.send(n); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("已投递至 %s", strings.Join(c.to, ", ")), nil
+}
+
+// send 通过 SMTP 投递一封邮件
+func (c *emailChannel) send(n *Notification) error {
+	var attachment []byte
+	if c.attachLog && n.ExecutionID != "" {
+		attachment = executionLogText(n.ExecutionID, n.redactor)
+	}
+	message, err := c.buildMessage(fitNotification(n, emailMaxBytes), attachment)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if c.username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("SMTP 服务器不支持认证")
+		}
+		if err := client.Auth(smtp.PlainAuth("", c.username, c.password, c.host)); err != nil {
+			return fmt.Errorf("SMTP 认证失败: %w", err)
+		}
+	}
+	if err := client.Mail(c.from.Address); err != nil {
+		return fmt.Errorf("SMTP 发件人被拒绝: %w", err)
+	}
+	for _, to := range c.to {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP 收件人被拒绝(%s): %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP 发送失败: %w", err)
+	}
+	return client.Quit()
+}
+
+// dial 建立 SMTP 连接（按配置使用隐式 TLS 或 STARTTLS），整个会话受 smtpTimeout 限制
+func (c *emailChannel) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(c.host, c.port)
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	tlsConfig := &tls.Config{ServerName: c.host}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if c.security == smtpSecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("连接 SMTP 服务器失败: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SMTP 握手失败: %w", err)
+	}
+	if c.security == smtpSecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("SMTP 服务器不支持 STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS 失败: %w", err)
+		}
+	}
+	return client, nil
+}
+
+// buildMessage 构造 MIME 邮件：HTML 正文，可选日志附件
+func (c *emailChannel) buildMessage(n *Notification, attachment []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	messageID, err := randomHex(16)
+	if err != nil {
+		return nil, fmt.Errorf("生成 Message-ID 失败: %w", err)
+	}
+	headers := []struct{ key, value string }{
+		{"From", c.from.String()},
+		{"To", strings.Join(c.to, ", ")},
+		{"Subject", mime.BEncoding.Encode("UTF-8", "[ScriptGuard] "+n.Title)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@scriptguard>", messageID)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/mixed; boundary=" + writer.Boundary()},
+	}
+	var head bytes.Buffer
+	for _, h := range headers {
+		fmt.Fprintf(&head, "%s: %s\r\n", h.key, h.value)
+	}
+	head.WriteString("\r\n")
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64Lines(part, []byte(emailHTML(n))); err != nil {
+		return nil, err
+	}
+
+	if len(attachment) > 0 {
+		name := fmt.Sprintf("execution-%s.log", n.ExecutionID)
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {fmt.Sprintf("text/plain; charset=UTF-8; name=%q", name)},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", name)},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64Lines(part, attachment); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return append(head.Bytes(), buf.Bytes()...), nil
+}
+
+// emailHTML 渲染 HTML 正文
+func emailHTML(n *Notification) string {
+	color := "#16a34a"
+	switch n.Level {
+	case NotificationLevelError:
+		color = "#dc2626"
+	case NotificationLevelWarning:
+		color = "#d97706"
+	}
+
+	var b strings.Builder
+	b.WriteString(`<html><body style="font-family:sans-serif;font-size:14px;color:#1f2937">`)
+	fmt.Fprintf(&b, `<h3 style="color:%s">%s</h3>`, color, html.EscapeString(n.Title))
+	if len(n.Fields) > 0 {
+		b.WriteString(`<table style="border-collapse:collapse">`)
+		for _, field := range n.Fields {
+			fmt.Fprintf(&b, `<tr><td style="padding:4px 12px 4px 0;color:#6b7280;white-space:nowrap">%s</td><td style="padding:4px 0">%s</td></tr>`,
+				html.EscapeString(field.Label), html.EscapeString(field.Value))
+		}
+		b.WriteString(`</table>`)
+	}
+	if n.Excerpt != "" {
+		b.WriteString(`<p style="margin-top:16px;color:#6b7280">最近日志</p>`)
+		fmt.Fprintf(&b, `<pre style="background:#f3f4f6;padding:12px;white-space:pre-wrap">%s</pre>`, html.EscapeString(n.Excerpt))
+	}
+	if n.Traceback != "" {
+		b.WriteString(`<p style="margin-top:16px;color:#6b7280">Traceback</p>`)
+		fmt.Fprintf(&b, `<pre style="background:#fef2f2;padding:12px;white-space:pre-wrap">%s</pre>`, html.EscapeString(n.Traceback))
+	}
+	if n.Note != "" {
+		fmt.Fprintf(&b, `<p>%s</p>`, html.EscapeString(n.Note))
+	}
+	if n.Markdown != "" {
+		fmt.Fprintf(&b, `<div style="margin-top:16px;white-space:pre-wrap">%s</div>`, html.EscapeString(n.Markdown))
+	}
+	b.WriteString(`</body></html>`)
+	return b.String()
+}
+
+// executionLogText 读取执行的完整日志（最多 maxEmailLogLines 行）并格式化为文本，读取失败返回 nil
+func executionLogText(executionID string, redactor *Redactor) []byte {
+	logs, err := QueryRecentLogs(executionID, "", maxEmailLogLines)
+	if err != nil || len(logs) == 0 {
+		return nil
+	}
+	var b bytes.Buffer
+	for _, entry := range logs {
+		fmt.Fprintf(&b, "[%s] [%s] %s\n",
+			entry.Timestamp.Format("2006-01-02 15:04:05"),
+			strings.ToUpper(string(entry.Level)),
+			redactor.Redact(entry.Content), // 兼容脱敏规则配置前已落库的日志
+		)
+	}
+	return b.Bytes()
+}
+
+// writeBase64Lines 以 76 字符折行写入 base64 内容
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > emailBase64LineLength {
+		if _, err := io.WriteString(w, encoded[:emailBase64LineLength]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[emailBase64LineLength:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
+
+// randomHex 生成随机十六进制串
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
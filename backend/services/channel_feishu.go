@@ -0,0 +1,122 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"scriptguard/backend/models"
+	"strconv"
+	"time"
+)
+
+func init() {
+	RegisterChannelType(models.ChannelTypeFeishu, newFeishuChannel)
+}
+
+// feishuChannel 飞书（Lark）自定义机器人，发送消息卡片
+// 配置项：webhook（必填）、secret（启用"签名校验"时填写）
+type feishuChannel struct {
+	webhook string
+	secret  string
+	client  *http.Client
+}
+
+func newFeishuChannel(config map[string]string, client *http.Client) (Channel, error) {
+	webhook, err := requireConfig(config, "webhook", "飞书 Webhook ")
+	if err != nil {
+		return nil, err
+	}
+	return &feishuChannel{webhook: webhook, secret: config["secret"], client: client}, nil
+}
+
+// feishuSign 计算签名：以 timestamp + "\n" + secret 为密钥对空串做 HmacSHA256 后 Base64
+func feishuSign(timestamp int64, secret string) string {
+	mac := hmac.New(sha256.New, []byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// feishuTemplate 卡片标题颜色
+func feishuTemplate(level string) string {
+	switch level {
+	case NotificationLevelError:
+		return "red"
+	case NotificationLevelWarning:
+		return "orange"
+	}
+	return "green"
+}
+
+// feishuMaxBytes 卡片消息请求体上限约 30KB（预留卡片结构开销）
+const feishuMaxBytes = 25000
+
+func (c *feishuChannel) Send(n *Notification) (string, error) {
+	n = fitNotification(n, feishuMaxBytes)
+	fields := make([]map[string]any, 0, len(n.Fields))
+	for _, field := range n.Fields {
+		fields = append(fields, map[string]any{
+			"is_short": len([]rune(field.Value)) <= 30,
+			"text":     map[string]string{"tag": "plain_text", "content": field.Label + ": " + field.Value},
+		})
+	}
+	elements := []map[string]any{{"tag": "div", "fields": fields}}
+	if n.Excerpt != "" {
+		elements = append(elements,
+			map[string]any{"tag": "hr"},
+			map[string]any{"tag": "div", "text": map[string]string{"tag": "plain_text", "content": "最近日志:\n" + n.Excerpt}},
+		)
+	}
+	if n.Traceback != "" {
+		elements = append(elements,
+			map[string]any{"tag": "hr"},
+			map[string]any{"tag": "div", "text": map[string]string{"tag": "plain_text", "content": "Traceback:\n" + n.Traceback}},
+		)
+	}
+	if n.Markdown != "" {
+		elements = append(elements, map[string]any{"tag": "markdown", "content": n.Markdown})
+	}
+	if n.Note != "" {
+		elements = append(elements, map[string]any{
+			"tag":      "note",
+			"elements": []map[string]string{{"tag": "plain_text", "content": n.Note}},
+		})
+	}
+
+	payload := map[string]any{
+		"msg_type": "interactive",
+		"card": map[string]any{
+			"config": map[string]bool{"wide_screen_mode": true},
+			"header": map[string]any{
+				"title":    map[string]string{"tag": "plain_text", "content": n.Title},
+				"template": feishuTemplate(n.Level),
+			},
+			"elements": elements,
+		},
+	}
+	if c.secret != "" {
+		timestamp := time.Now().Unix()
+		payload["timestamp"] = strconv.FormatInt(timestamp, 10)
+		payload["sign"] = feishuSign(timestamp, c.secret)
+	}
+
+	body, err := postWebhook(c.client, c.webhook, payload)
+	if err != nil {
+		return string(body), err
+	}
+	// 飞书在 HTTP 200 中通过 code 返回业务错误（如签名校验失败）
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if json.Unmarshal(body, &result) == nil && result.Code != 0 {
+		return string(body), fmt.Errorf("飞书返回错误: code=%d, msg=%s", result.Code, result.Msg)
+	}
+	return string(body), nil
+}
+
+// RateLimit 机器人每分钟最多接收 100 条消息
+func (c *feishuChannel) RateLimit() (int, time.Duration) {
+	return 100, time.Minute
+}
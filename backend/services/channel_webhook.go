@@ -0,0 +1,137 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"scriptguard/backend/models"
+	"strings"
+	"text/template"
+	"time"
+)
+
+func init() {
+	RegisterChannelType(models.ChannelTypeWebhook, newGenericWebhookChannel)
+}
+
+// defaultWebhookBody 未配置请求体模板时使用的模板
+const defaultWebhookBody = `{"title": {{json .Title}}, "level": {{json .Level}}, "text": {{json .Text}}}`
+
+// WebhookTemplateData 自定义 webhook 请求体模板的数据
+type WebhookTemplateData struct {
+	Title     string
+	Level     string // error / warning / info
+	Text      string // 纯文本正文（与钉钉、企业微信文本消息一致）
+	Excerpt   string // 最近日志摘录
+	Traceback string // Python traceback（未检测到时为空）
+	Markdown  string // Markdown 正文（如执行汇总，其他通知为空）
+	Fields    map[string]string
+	Task      *models.Task
+	Execution *models.Execution
+	Time      time.Time
+}
+
+// webhookFuncs 请求体模板函数
+var webhookFuncs = template.FuncMap{
+	// json 将值编码为 JSON（字符串会带引号并转义），用于安全地嵌入请求体
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// genericWebhookChannel 通用 HTTP 渠道：请求方法、请求头与请求体均可自定义，
+// 用于对接自建事件系统、Slack 兼容接口、Telegram 等
+// 配置项：url（必填）、method（POST / PUT / PATCH，默认 POST）、
+// headers（每行一个 "名称: 值"）、body（Go text/template 模板，渲染结果须为合法 JSON）
+type genericWebhookChannel struct {
+	url    string
+	method string
+	header http.Header
+	body   *template.Template
+	client *http.Client
+}
+
+func newGenericWebhookChannel(config map[string]string, client *http.Client) (Channel, error) {
+	target, err := requireConfig(config, "url", "Webhook 地址")
+	if err != nil {
+		return nil, err
+	}
+	if u, err := url.Parse(target); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("Webhook 地址非法: %s", target)
+	}
+
+	method := strings.ToUpper(strings.TrimSpace(config["method"]))
+	switch method {
+	case "":
+		method = http.MethodPost
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return nil, fmt.Errorf("不支持的请求方法: %s", method)
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	for _, line := range strings.Split(config["headers"], "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("请求头格式非法（应为 名称: 值）: %s", line)
+		}
+		header.Set(key, strings.TrimSpace(value))
+	}
+
+	text := config["body"]
+	if strings.TrimSpace(text) == "" {
+		text = defaultWebhookBody
+	}
+	body, err := template.New("body").Funcs(webhookFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("请求体模板非法: %w", err)
+	}
+
+	return &genericWebhookChannel{url: target, method: method, header: header, body: body, client: client}, nil
+}
+
+func (c *genericWebhookChannel) Send(n *Notification) (string, error) {
+	data := WebhookTemplateData{
+		Title:     n.Title,
+		Level:     n.Level,
+		Text:      n.Text(),
+		Excerpt:   n.Excerpt,
+		Traceback: n.Traceback,
+		Markdown:  n.Markdown,
+		Fields:    make(map[string]string, len(n.Fields)),
+		Task:      n.Task,
+		Execution: n.Execution,
+		Time:      NowBeijing(),
+	}
+	for _, field := range n.Fields {
+		data.Fields[field.Label] = field.Value
+	}
+	// 测试通知等没有关联任务时使用空值，避免模板访问字段时报错
+	if data.Task == nil {
+		data.Task = &models.Task{}
+	}
+	if data.Execution == nil {
+		data.Execution = &models.Execution{}
+	}
+
+	var buf bytes.Buffer
+	if err := c.body.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染请求体失败: %w", err)
+	}
+	// 模板可直接引用任务与执行记录的原始字段，渲染后再统一脱敏
+	body := []byte(n.redactor.Redact(buf.String()))
+	if c.header.Get("Content-Type") == "application/json" && !json.Valid(body) {
+		return "", fmt.Errorf("请求体不是合法 JSON: %s", truncateRunes(string(body), 200))
+	}
+
+	resp, err := doWebhook(c.client, c.method, c.url, c.header, body)
+	return string(resp), err
+}
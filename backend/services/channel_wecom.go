@@ -0,0 +1,83 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"scriptguard/backend/models"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterChannelType(models.ChannelTypeWeCom, newWeComChannel)
+}
+
+// weComChannel 企业微信群机器人
+type weComChannel struct {
+	webhook string
+	client  *http.Client
+}
+
+func newWeComChannel(config map[string]string, client *http.Client) (Channel, error) {
+	webhook, err := requireConfig(config, "webhook", "企业微信 Webhook ")
+	if err != nil {
+		return nil, err
+	}
+	return &weComChannel{webhook: webhook, client: client}, nil
+}
+
+// 企业微信消息内容上限：文本 2048 字节、markdown 4096 字节（预留换行等开销）
+const (
+	weComMaxBytes         = 1900
+	weComMarkdownMaxBytes = 3900
+)
+
+func (c *weComChannel) Send(n *Notification) (string, error) {
+	var payload map[string]any
+	if n.Markdown != "" {
+		payload = map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"content": weComMarkdown(fitNotification(n, weComMarkdownMaxBytes))},
+		}
+	} else {
+		payload = map[string]any{
+			"msgtype": "text",
+			"text": map[string]string{
+				"content": fitNotification(n, weComMaxBytes).fullText(),
+			},
+		}
+	}
+	body, err := postWebhook(c.client, c.webhook, payload)
+	if err != nil {
+		return string(body), err
+	}
+	// 企业微信在 HTTP 200 中通过 errcode 返回业务错误（如 webhook key 无效、消息过长）
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if json.Unmarshal(body, &result) == nil && result.ErrCode != 0 {
+		return string(body), fmt.Errorf("企业微信返回错误: errcode=%d, errmsg=%s", result.ErrCode, result.ErrMsg)
+	}
+	return string(body), nil
+}
+
+// RateLimit 机器人每分钟最多接收 20 条消息
+func (c *weComChannel) RateLimit() (int, time.Duration) {
+	return 20, time.Minute
+}
+
+// weComMarkdown 渲染带 Markdown 正文的通知（如执行汇总）
+func weComMarkdown(n *Notification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n", n.Title)
+	for _, field := range n.Fields {
+		fmt.Fprintf(&b, "> %s: %s\n", field.Label, field.Value)
+	}
+	if n.Note != "" {
+		fmt.Fprintf(&b, "\n%s\n", n.Note)
+	}
+	fmt.Fprintf(&b, "\n%s", n.Markdown)
+	return b.String()
+}
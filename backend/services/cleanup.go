@@ -27,6 +27,7 @@ func (s *CleanupService) Start() {
 	// SG-012: 检查 AddFunc 错误
 	_, err := s.cron.AddFunc("0 0 2 * * *", func() {
 		s.cleanupOldLogs()
+		s.cleanupOldArtifacts()
 	})
 	if err != nil {
 		log.Printf("添加清理任务失败: %v", err)
@@ -69,6 +70,13 @@ func (s *CleanupService) cleanupOldLogs() {
 		log.Printf("已清理 %d 条过期日志", result.RowsAffected)
 	}
 
+	// 删除过期的日志文件（文件存储后端）
+	if removed, err := RemoveLogFilesBefore(cutoffDate); err != nil {
+		log.Printf("清理旧日志文件失败: %v", err)
+	} else if removed > 0 {
+		log.Printf("已清理 %d 个过期日志文件", removed)
+	}
+
 	// 删除旧的执行记录（与日志使用同一保留天数；仅删除已结束的记录）
 	execResult := db.Where("end_time IS NOT NULL AND end_time < ?", cutoffDate).Delete(&models.Execution{})
 	if execResult.Error != nil {
@@ -76,6 +84,37 @@ func (s *CleanupService) cleanupOldLogs() {
 	} else if execResult.RowsAffected > 0 {
 		log.Printf("已清理 %d 条过期执行记录", execResult.RowsAffected)
 	}
+
+	// 删除已结束投递的告警记录（待发送的保留）
+	notifyResult := db.Where("status IN ? AND created_at < ?",
+		[]string{models.NotificationSent, models.NotificationFailed}, cutoffDate).Delete(&models.Notification{})
+	if notifyResult.Error != nil {
+		log.Printf("清理旧告警记录失败: %v", notifyResult.Error)
+	} else if notifyResult.RowsAffected > 0 {
+		log.Printf("已清理 %d 条过期告警记录", notifyResult.RowsAffected)
+	}
+}
+
+// cleanupOldArtifacts 按产物保留天数清理执行产物
+func (s *CleanupService) cleanupOldArtifacts() {
+	var config models.Config
+	err := database.GetDB().Where("key = ?", models.ConfigKeyArtifactRetentionDays).First(&config).Error
+	if err != nil {
+		log.Printf("读取产物保留天数配置失败: %v，使用默认值 30 天", err)
+		config.Value = "30"
+	}
+
+	retentionDays, err := strconv.Atoi(config.Value)
+	if err != nil || retentionDays < 1 {
+		retentionDays = 30
+	}
+
+	cutoffDate := NowBeijing().AddDate(0, 0, -retentionDays)
+	if removed, err := RemoveArtifactsBefore(cutoffDate); err != nil {
+		log.Printf("清理过期产物失败: %v", err)
+	} else if removed > 0 {
+		log.Printf("已清理 %d 个过期产物", removed)
+	}
 }
 
 // CleanupDatabase 手动触发数据库清理
@@ -83,5 +122,13 @@ func (s *CleanupService) CleanupDatabase() error {
 	db := database.GetDB()
 
 	// VACUUM SQLite数据库（压缩和优化）
-	return db.Exec("VACUUM").Error
+	if err := db.Exec("VACUUM").Error; err != nil {
+		return err
+	}
+
+	// VACUUM 可能改变 logs 的 rowid，需重建全文索引
+	if database.LogSearchEnabled() {
+		return database.RebuildLogSearchIndex()
+	}
+	return nil
 }
@@ -9,7 +9,6 @@ import (
 	"regexp"
 	"scriptguard/backend/models"
 	"strings"
-	"syscall"
 	"time"
 )
 
@@ -30,7 +29,7 @@ func (s *CondaService) ScanEnvironments() ([]models.Environment, error) {
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "conda", "env", "list")
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	cmd.SysProcAttr = newProcAttr()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 	cmd.Stdout = &out
@@ -85,6 +84,6 @@ func (s *CondaService) ValidateEnvironment(envName string) bool {
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "conda", "run", "-n", envName, "python", "--version")
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	cmd.SysProcAttr = newProcAttr()
 	return cmd.Run() == nil
 }
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// pageCursor 键集分页游标：按 (时间, ID) 定位上一页的边界记录
+type pageCursor struct {
+	Time time.Time `json:"t"`
+	ID   string    `json:"id"`
+}
+
+// encodeCursor 编码为对前端不透明的字符串
+func encodeCursor(t time.Time, id string) string {
+	data, _ := json.Marshal(pageCursor{Time: t.In(BeijingLocation), ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析游标，空字符串返回 nil
+func decodeCursor(s string) (*pageCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("无效的分页游标: %w", err)
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("无效的分页游标: %w", err)
+	}
+	if c.ID == "" || c.Time.IsZero() {
+		return nil, fmt.Errorf("无效的分页游标: 缺少时间或 ID")
+	}
+	c.Time = c.Time.In(BeijingLocation)
+	return &c, nil
+}
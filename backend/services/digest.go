@@ -0,0 +1,365 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"scriptguard/backend/database"
+	"scriptguard/backend/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// 汇总周期
+const (
+	DigestDaily  = "daily"  // 前一天
+	DigestWeekly = "weekly" // 上一周（周一至周日）
+)
+
+// 汇总内容限制
+const (
+	digestEvent       = "digest"
+	digestMaxTasks    = 30 // 任务统计最多列出的任务数（按失败次数排序）
+	digestSlowestRuns = 5
+	defaultDigestTime = "08:30"
+)
+
+// DigestTaskStat 单个任务在汇总周期内的执行统计
+type DigestTaskStat struct {
+	TaskID        string  `json:"task_id"`
+	TaskName      string  `json:"task_name"`
+	Runs          int     `json:"runs"`
+	Failures      int     `json:"failures"`
+	Warnings      int     `json:"warnings"`
+	SuccessRate   float64 `json:"success_rate"` // 未失败的执行占比（百分比）
+	AvgDurationMs int64   `json:"avg_duration_ms"`
+	MaxDurationMs int64   `json:"max_duration_ms"`
+}
+
+// DigestRun 汇总中列出的单次执行
+type DigestRun struct {
+	ExecutionID string                 `json:"execution_id"`
+	TaskName    string                 `json:"task_name"`
+	Status      models.ExecutionStatus `json:"status"`
+	StartTime   time.Time              `json:"start_time"`
+	DurationMs  int64                  `json:"duration_ms"`
+}
+
+// Digest 一个周期内的执行汇总
+type Digest struct {
+	Period      string           `json:"period"`
+	Start       time.Time        `json:"start"`
+	End         time.Time        `json:"end"`
+	Runs        int              `json:"runs"`
+	Failures    int              `json:"failures"`
+	SuccessRate float64          `json:"success_rate"`
+	Tasks       []DigestTaskStat `json:"tasks"`     // 按失败次数、执行次数降序
+	Slowest     []DigestRun      `json:"slowest"`   // 耗时最长的执行
+	NeverRan    []string         `json:"never_ran"` // 周期内一次都没有执行的已启用任务
+}
+
+// digestRange 汇总周期的起止时间（北京时间，左闭右开）
+func digestRange(period string, now time.Time) (time.Time, time.Time, error) {
+	now = now.In(BeijingLocation)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, BeijingLocation)
+	switch period {
+	case DigestDaily:
+		return today.AddDate(0, 0, -1), today, nil
+	case DigestWeekly:
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return monday.AddDate(0, 0, -7), monday, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("未知的汇总周期: %s", period)
+}
+
+// BuildDigest 统计 now 所在日（周）之前一个周期内已结束的执行
+func BuildDigest(period string, now time.Time) (*Digest, error) {
+	start, end, err := digestRange(period, now)
+	if err != nil {
+		return nil, err
+	}
+	db := database.GetDB()
+
+	var tasks []models.Task
+	if err := db.Find(&tasks).Error; err != nil {
+		return nil, fmt.Errorf("查询任务失败: %w", err)
+	}
+	var executions []models.Execution
+	err = db.Select("id", "task_id", "status", "start_time", "duration_ms").
+		Where("start_time >= ? AND start_time < ? AND end_time IS NOT NULL", start, end).
+		Find(&executions).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询执行记录失败: %w", err)
+	}
+
+	names := make(map[string]string, len(tasks))
+	for _, task := range tasks {
+		names[task.ID] = task.Name
+	}
+	taskName := func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		return fmt.Sprintf("已删除的任务(%.8s)", id)
+	}
+
+	digest := &Digest{Period: period, Start: start, End: end}
+	stats := make(map[string]*DigestTaskStat)
+	for _, e := range executions {
+		stat := stats[e.TaskID]
+		if stat == nil {
+			stat = &DigestTaskStat{TaskID: e.TaskID, TaskName: taskName(e.TaskID)}
+			stats[e.TaskID] = stat
+		}
+		stat.Runs++
+		stat.AvgDurationMs += e.DurationMs // 先累加，最后求均值
+		stat.MaxDurationMs = max(stat.MaxDurationMs, e.DurationMs)
+		switch e.Status {
+		case models.StatusFailed:
+			stat.Failures++
+			digest.Failures++
+		case models.StatusWarning:
+			stat.Warnings++
+		}
+	}
+	digest.Runs = len(executions)
+	digest.SuccessRate = successRate(digest.Runs, digest.Failures)
+
+	for _, stat := range stats {
+		stat.AvgDurationMs /= int64(stat.Runs)
+		stat.SuccessRate = successRate(stat.Runs, stat.Failures)
+		digest.Tasks = append(digest.Tasks, *stat)
+	}
+	sort.Slice(digest.Tasks, func(i, j int) bool {
+		a, b := digest.Tasks[i], digest.Tasks[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return a.TaskName < b.TaskName
+	})
+
+	sort.Slice(executions, func(i, j int) bool { return executions[i].DurationMs > executions[j].DurationMs })
+	for _, e := range executions[:min(len(executions), digestSlowestRuns)] {
+		digest.Slowest = append(digest.Slowest, DigestRun{
+			ExecutionID: e.ID,
+			TaskName:    taskName(e.TaskID),
+			Status:      e.Status,
+			StartTime:   e.StartTime,
+			DurationMs:  e.DurationMs,
+		})
+	}
+
+	for _, task := range tasks {
+		if task.Enabled && task.CreatedAt.Before(end) && stats[task.ID] == nil {
+			digest.NeverRan = append(digest.NeverRan, task.Name)
+		}
+	}
+	sort.Strings(digest.NeverRan)
+	return digest, nil
+}
+
+// successRate 未失败的执行占比（百分比，无执行时为 0）
+func successRate(runs, failures int) float64 {
+	if runs == 0 {
+		return 0
+	}
+	return float64(runs-failures) / float64(runs) * 100
+}
+
+// Markdown 渲染任务统计、耗时最长的执行与未执行的任务（只使用各渠道通用的标题、加粗与列表语法）
+func (d *Digest) Markdown() string {
+	var b strings.Builder
+	b.WriteString("#### 任务统计\n\n")
+	if len(d.Tasks) == 0 {
+		b.WriteString("- 本周期内没有执行记录\n")
+	}
+	for _, stat := range d.Tasks[:min(len(d.Tasks), digestMaxTasks)] {
+		fmt.Fprintf(&b, "- **%s** 执行 %d 次，失败 %d 次", stat.TaskName, stat.Runs, stat.Failures)
+		if stat.Warnings > 0 {
+			fmt.Fprintf(&b, "，警告 %d 次", stat.Warnings)
+		}
+		fmt.Fprintf(&b, "，成功率 %.1f%%，平均耗时 %s\n", stat.SuccessRate, formatDurationMs(stat.AvgDurationMs))
+	}
+	if rest := len(d.Tasks) - digestMaxTasks; rest > 0 {
+		fmt.Fprintf(&b, "- 其余 %d 个任务未列出\n", rest)
+	}
+
+	if len(d.Slowest) > 0 {
+		b.WriteString("\n#### 耗时最长\n\n")
+		for i, run := range d.Slowest {
+			fmt.Fprintf(&b, "%d. **%s** %s（%s 开始，%s）\n", i+1, run.TaskName, formatDurationMs(run.DurationMs),
+				run.StartTime.In(BeijingLocation).Format("01-02 15:04"), statusLabel(run.Status))
+		}
+	}
+
+	if len(d.NeverRan) > 0 {
+		b.WriteString("\n#### 未执行的任务\n\n")
+		for _, name := range d.NeverRan {
+			fmt.Fprintf(&b, "- %s\n", name)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Notification 汇总的通知内容
+func (d *Digest) Notification() *Notification {
+	title := "📊 每日执行汇总"
+	rangeText := d.Start.Format("2006-01-02")
+	if d.Period == DigestWeekly {
+		title = "📊 每周执行汇总"
+		rangeText += " ~ " + d.End.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	level := NotificationLevelInfo
+	if d.Failures > 0 {
+		level = NotificationLevelWarning
+	}
+	return &Notification{
+		Title: fmt.Sprintf("%s（%s）", title, rangeText),
+		Event: digestEvent,
+		Level: level,
+		Fields: []NotificationField{
+			{"主机", hostName()},
+			{"执行次数", strconv.Itoa(d.Runs)},
+			{"失败次数", strconv.Itoa(d.Failures)},
+			{"成功率", fmt.Sprintf("%.1f%%", d.SuccessRate)},
+		},
+		Markdown: d.Markdown(),
+	}
+}
+
+// ParseDigestTime 解析汇总发送时间（HH:MM）
+func ParseDigestTime(value string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, 0, fmt.Errorf("汇总发送时间格式应为 HH:MM: %s", value)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// ParseDigestChannels 解析汇总发送的渠道 ID 列表（逗号分隔）
+func ParseDigestChannels(value string) []string {
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ValidateDigestChannels 校验汇总发送的渠道均存在
+func ValidateDigestChannels(value string) error {
+	ids := ParseDigestChannels(value)
+	if len(ids) == 0 {
+		return nil
+	}
+	var count int64
+	if err := database.GetDB().Model(&models.NotifyChannel{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(ids) {
+		return fmt.Errorf("汇总发送的渠道不存在")
+	}
+	return nil
+}
+
+// DigestService 定时发送执行汇总
+type DigestService struct {
+	mu       sync.Mutex
+	cron     *cron.Cron
+	notifier *NotifierService
+	entries  []cron.EntryID
+}
+
+// NewDigestService 创建汇总服务
+func NewDigestService(notifier *NotifierService) *DigestService {
+	return &DigestService{
+		cron:     cron.New(cron.WithSeconds(), cron.WithLocation(BeijingLocation)),
+		notifier: notifier,
+	}
+}
+
+// Start 启动汇总服务
+func (s *DigestService) Start() {
+	if err := s.Reload(); err != nil {
+		log.Printf("加载汇总配置失败: %v", err)
+	}
+	s.cron.Start()
+}
+
+// Stop 停止汇总服务
+func (s *DigestService) Stop() {
+	s.cron.Stop()
+}
+
+// Reload 按配置重新安排每日、每周汇总（用于启动及配置变更后热更新）
+func (s *DigestService) Reload() error {
+	var configs []models.Config
+	keys := []string{models.ConfigKeyDigestDailyEnabled, models.ConfigKeyDigestWeeklyEnabled, models.ConfigKeyDigestTime}
+	if err := database.GetDB().Where("key IN ?", keys).Find(&configs).Error; err != nil {
+		return err
+	}
+	config := make(map[string]string, len(configs))
+	for _, c := range configs {
+		config[c.Key] = strings.TrimSpace(c.Value)
+	}
+	if config[models.ConfigKeyDigestTime] == "" {
+		config[models.ConfigKeyDigestTime] = defaultDigestTime
+	}
+	hour, minute, err := ParseDigestTime(config[models.ConfigKeyDigestTime])
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range s.entries {
+		s.cron.Remove(id)
+	}
+	s.entries = nil
+
+	schedules := []struct {
+		period, key, spec string
+	}{
+		{DigestDaily, models.ConfigKeyDigestDailyEnabled, fmt.Sprintf("0 %d %d * * *", minute, hour)},
+		{DigestWeekly, models.ConfigKeyDigestWeeklyEnabled, fmt.Sprintf("0 %d %d * * 1", minute, hour)},
+	}
+	for _, item := range schedules {
+		if !strings.EqualFold(config[item.key], "true") {
+			continue
+		}
+		period := item.period
+		id, err := s.cron.AddFunc(item.spec, func() {
+			if err := s.Send(period); err != nil {
+				log.Printf("发送执行汇总失败(period=%s): %v", period, err)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("添加汇总任务失败: %w", err)
+		}
+		s.entries = append(s.entries, id)
+	}
+	return nil
+}
+
+// Send 立即统计并发送一期汇总（发送到配置的渠道，未配置时发送到全部已启用渠道）
+func (s *DigestService) Send(period string) error {
+	digest, err := BuildDigest(period, NowBeijing())
+	if err != nil {
+		return err
+	}
+	var config models.Config
+	err = database.GetDB().Where("key = ?", models.ConfigKeyDigestChannels).Limit(1).Find(&config).Error
+	if err != nil {
+		return err
+	}
+	s.notifier.broadcast(digest.Notification(), ParseDigestChannels(config.Value))
+	return nil
+}
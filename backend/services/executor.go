@@ -9,23 +9,24 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"scriptguard/backend/database"
 	"scriptguard/backend/models"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"golang.org/x/text/encoding/simplifiedchinese"
+	"gorm.io/gorm/clause"
 )
 
 // 日志处理常量
 const (
-	maxLogLineBytes  = 1024 * 1024           // 单行日志最大长度（超过截断，但继续 drain）
-	logQueueSize     = 2000                  // 单次执行日志写入队列容量（满则丢弃并统计）
-	logBatchSize     = 200                   // 批量写入条数
+	maxLogLineBytes  = 1024 * 1024            // 单行日志最大长度（超过截断，但继续 drain）
+	logQueueSize     = 2000                   // 单次执行日志写入队列容量（满则暂存到磁盘，结束后补录）
+	logBatchSize     = 200                    // 批量写入条数
 	logFlushInterval = 200 * time.Millisecond // 批量写入间隔
 )
 
@@ -33,6 +34,13 @@ type ExecutorService struct {
 	logChan chan LogMessage
 	limiter *ConcurrencyLimiter
 	timeout time.Duration // 0 表示不限制
+	logDir  string        // 日志文件根目录（溢出暂存、文件存储后端等）
+	storage string        // 日志存储后端：database / file
+
+	artifactDir string       // 执行产物根目录
+	onStall     StallHandler // 检测到执行卡住时的回调
+
+	redactor *Redactor // 敏感信息脱敏
 }
 
 type LogMessage struct {
@@ -41,6 +49,8 @@ type LogMessage struct {
 	Timestamp   time.Time
 	Level       string
 	Content     string
+	Stream      string
+	Fields      map[string]any
 }
 
 func NewExecutorService() *ExecutorService {
@@ -48,9 +58,56 @@ func NewExecutorService() *ExecutorService {
 		logChan: make(chan LogMessage, 1000),
 		limiter: NewConcurrencyLimiter(5), // 默认最大并发 5
 		timeout: 0,                        // 默认不限制超时
+		logDir:  filepath.Join(os.TempDir(), "ScriptGuard", "logs"),
+		storage: models.LogStorageDatabase,
+
+		artifactDir: filepath.Join(os.TempDir(), "ScriptGuard", "artifacts"),
 	}
 }
 
+// SetLogDir 设置日志文件根目录
+func (s *ExecutorService) SetLogDir(dir string) {
+	s.logDir = dir
+}
+
+// SetStallHandler 设置执行卡住（长时间无输出）时的回调
+func (s *ExecutorService) SetStallHandler(handler StallHandler) {
+	s.onStall = handler
+}
+
+// SetRedactor 设置敏感信息脱敏器
+func (s *ExecutorService) SetRedactor(redactor *Redactor) {
+	s.redactor = redactor
+}
+
+// SetLogStorage 设置日志存储后端（仅影响之后开始的执行）
+func (s *ExecutorService) SetLogStorage(storage string) {
+	s.storage = storage
+}
+
+// newLogSink 按当前配置创建单次执行的日志持久化后端
+func (s *ExecutorService) newLogSink(execution *models.Execution) logSink {
+	if s.storage == models.LogStorageFile {
+		return newFileLogSink(filepath.Join(s.logDir, "files"), execution)
+	}
+	return dbLogSink{}
+}
+
+// spillDir 日志溢出暂存目录
+func (s *ExecutorService) spillDir() string {
+	return filepath.Join(s.logDir, "spill")
+}
+
+// outputDir 脚本输出文件目录（SG_OUTPUT_FILE）
+func (s *ExecutorService) outputDir() string {
+	return filepath.Join(s.logDir, "outputs")
+}
+
+// saveLogBatch 批量写入日志到数据库（ID 已存在的跳过，溢出日志补录重试时不会重复）
+func saveLogBatch(batch []*models.Log) error {
+	return database.GetDB().Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(batch, logBatchSize).Error
+}
+
 // SetMaxConcurrency 设置最大并发数
 func (s *ExecutorService) SetMaxConcurrency(max int) {
 	s.limiter.SetMax(max)
@@ -61,14 +118,40 @@ func (s *ExecutorService) SetTimeout(timeout time.Duration) {
 	s.timeout = timeout
 }
 
-// TryExecute 尝试执行（非阻塞，达到上限时返回 false）
-func (s *ExecutorService) TryExecute() bool {
-	return s.limiter.TryAcquire()
+// GetMaxConcurrency 获取最大并发数
+func (s *ExecutorService) GetMaxConcurrency() int {
+	return s.limiter.GetMax()
+}
+
+// acquireRequest 任务的并发申请
+func acquireRequest(task *models.Task) AcquireRequest {
+	return AcquireRequest{
+		TaskID:   task.ID,
+		TaskName: task.Name,
+		Priority: task.Priority,
+		Weight:   task.Weight,
+		Pools:    task.Pools,
+	}
 }
 
-// ReleaseExecution 释放执行权限
-func (s *ExecutorService) ReleaseExecution() {
-	s.limiter.Release()
+// TryExecute 尝试执行（非阻塞，槽位不足时返回 false）
+func (s *ExecutorService) TryExecute(task *models.Task) bool {
+	return s.limiter.TryAcquire(acquireRequest(task))
+}
+
+// WaitExecute 排队等待执行权限，按任务优先级授予
+func (s *ExecutorService) WaitExecute(task *models.Task) error {
+	return s.limiter.Acquire(acquireRequest(task))
+}
+
+// ReleaseExecution 释放执行权限（task 需与获取时一致）
+func (s *ExecutorService) ReleaseExecution(task *models.Task) {
+	s.limiter.Release(acquireRequest(task))
+}
+
+// QueuedRuns 排队中的执行（按授予顺序）
+func (s *ExecutorService) QueuedRuns() []QueuedRun {
+	return s.limiter.Queued()
 }
 
 // ExecuteScript 执行Python脚本
@@ -80,11 +163,45 @@ func (s *ExecutorService) ExecuteScript(task *models.Task) (*models.Execution, e
 		Status:    models.StatusRunning,
 	}
 
+	// 渲染脚本参数与环境变量（可引用上游任务的输出值）
+	args, extraEnv, err := renderLaunch(task, LaunchData{
+		TaskID:      task.ID,
+		TaskName:    task.Name,
+		ExecutionID: execution.ID,
+	})
+	if err != nil {
+		execution.Status = models.StatusFailed
+		execution.ErrorMessage = err.Error()
+		now := NowBeijing()
+		execution.EndTime = &now
+		return execution, err
+	}
+
+	// 成功判定规则（输出规则在读取输出时逐行匹配）
+	matcher, err := newSuccessMatcher(task.SuccessRules)
+	if err != nil {
+		execution.Status = models.StatusFailed
+		execution.ErrorMessage = "成功判定规则非法: " + err.Error()
+		now := NowBeijing()
+		execution.EndTime = &now
+		return execution, err
+	}
+
+	// 脚本可向 SG_OUTPUT_FILE 写入输出值，执行结束后读取
+	outputFile := filepath.Join(s.outputDir(), execution.ID+".out")
+	if err := os.MkdirAll(s.outputDir(), 0755); err != nil {
+		log.Printf("创建输出目录失败: %v", err)
+	}
+	defer os.Remove(outputFile)
+
 	// SG-004: 支持超时控制
 	// SG-030: Windows 下通过 cmd /c 设置 UTF-8 代码页，确保所有输出为 UTF-8
 	var cmd *exec.Cmd
 	var cancel context.CancelFunc
 	cmdLine := fmt.Sprintf("chcp 65001 >nul && conda run -n %s python %s", task.CondaEnv, task.ScriptPath)
+	if args != "" {
+		cmdLine += " " + args
+	}
 	if s.timeout > 0 {
 		ctx, c := context.WithTimeout(context.Background(), s.timeout)
 		cancel = c
@@ -99,8 +216,13 @@ func (s *ExecutorService) ExecuteScript(task *models.Task) (*models.Execution, e
 	cmd.Env = append(os.Environ(),
 		"PYTHONIOENCODING=utf-8",
 		"PYTHONUTF8=1", // Python 3.7+ UTF-8 模式
+		envOutputFile+"="+outputFile,
+		envExecutionID+"="+execution.ID,
+		envTaskID+"="+task.ID,
 	)
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	cmd.Env = append(cmd.Env, extraEnv...)
+	// Windows 下进程以挂起状态启动，加入作业对象后再恢复
+	cmd.SysProcAttr = newScriptProcAttr()
 
 	// SG-005: 检查 Pipe 错误
 	stdout, err := cmd.StdoutPipe()
@@ -132,9 +254,22 @@ func (s *ExecutorService) ExecuteScript(task *models.Task) (*models.Execution, e
 		return execution, err
 	}
 
+	// 应用任务的资源限制，并采样进程树资源占用（CPU、内存、I/O）
+	monitor, limitErr := newResourceMonitor(cmd.Process, limitsFromTask(task))
+	if limitErr != nil {
+		s.saveSystemLog(execution.ID, task.ID, models.LogLevelWarning, limitErr.Error())
+	}
+	// 恢复失败时进程无法继续运行，直接结束，由下方 Wait 统一收尾
+	resumeErr := resumeProcess(cmd.Process)
+	if resumeErr != nil {
+		killProcessTree(cmd.Process)
+	}
+
 	// 单次执行的日志写入队列（避免高输出阻塞 pipe 读取）
 	logQueue := make(chan *models.Log, logQueueSize)
 	writerDone := make(chan struct{})
+	spill := newLogSpill(s.spillDir(), execution.ID)
+	sink := s.newLogSink(execution)
 
 	go func() {
 		defer close(writerDone)
@@ -148,7 +283,7 @@ func (s *ExecutorService) ExecuteScript(task *models.Task) (*models.Execution, e
 			if len(batch) == 0 {
 				return
 			}
-			if err := database.GetDB().CreateInBatches(batch, logBatchSize).Error; err != nil {
+			if err := sink.Write(batch); err != nil {
 				log.Printf("批量保存日志失败(execution_id=%s): %v", execution.ID, err)
 			}
 			batch = batch[:0]
@@ -167,6 +302,12 @@ func (s *ExecutorService) ExecuteScript(task *models.Task) (*models.Execution, e
 				}
 			case <-ticker.C:
 				flush()
+				if err := sink.Flush(); err != nil {
+					log.Printf("写入缓冲日志失败(execution_id=%s): %v", execution.ID, err)
+				}
+				if err := spill.Flush(); err != nil {
+					log.Printf("写入日志溢出文件失败(execution_id=%s): %v", execution.ID, err)
+				}
 			}
 		}
 	}()
@@ -177,60 +318,109 @@ func (s *ExecutorService) ExecuteScript(task *models.Task) (*models.Execution, e
 
 	droppedStdout := 0
 	droppedStderr := 0
+	report := &executionReport{redactor: s.redactor}
+
+	// 无输出看门狗：连续无输出超过任务配置的分钟数时标记卡住、告警，并按配置终止进程
+	watchdog := newStallWatchdog(task.StallMinutes)
+	watchdog.Start(func(idle time.Duration) bool {
+		minutes := int(idle.Minutes())
+		kill := task.StallAction == models.StallActionKill
+		content := fmt.Sprintf("已连续 %d 分钟无输出，疑似卡住", minutes)
+		if kill {
+			content += "，正在终止进程"
+		}
+		s.saveSystemLog(execution.ID, task.ID, models.LogLevelWarning, content)
+
+		// 先尝试终止，再按实际结果告警
+		var killErr error
+		if kill {
+			if killErr = killProcessTree(cmd.Process); killErr != nil {
+				log.Printf("终止卡住的进程失败(execution_id=%s): %v", execution.ID, killErr)
+				s.saveSystemLog(execution.ID, task.ID, models.LogLevelError, fmt.Sprintf("终止卡住的进程失败: %v", killErr))
+			}
+		}
 
-	// 实时读取 stdout（单行最多 1MB，超出部分截断但继续 drain）
-	go func() {
-		defer wg.Done()
-		reader := bufio.NewReaderSize(stdout, 64*1024)
-
-		for {
-			line, truncated, readAny, readErr := readLineWithLimit(reader, maxLogLineBytes)
-			if readAny {
-				content := line
-				if truncated {
-					content += " ...(已截断)"
+		if s.onStall != nil {
+			snapshot := *execution
+			snapshot.StalledAt = watchdog.StalledAt()
+			go s.onStall(task, &snapshot, idle, killErr)
+		}
+		return kill && killErr == nil
+	})
+
+	// handleLine 处理一行输出：拦截上报指令，其余脱敏、解析后推送并入队落库
+	handleLine := func(level models.LogLevel, content string, dropped *int) {
+		watchdog.Touch()
+
+		// 上报协议指令：记录到执行结果并推送事件，不计入普通日志
+		// 进度说明与指标会展示和推送，与日志一样先脱敏；输出值由 report 保留原值供下游任务引用
+		if cmd := parseProtocolLine(content); cmd != nil {
+			cmd.Message = s.redactor.Redact(cmd.Message)
+			if cmd.Name != protocolOutput {
+				for key, value := range cmd.Values {
+					cmd.Values[key] = s.redactor.Redact(value)
 				}
+			}
+			report.Apply(cmd)
+			if event, ok := cmd.streamEvent(execution); ok {
+				s.logChan <- event
+			}
+			return
+		}
 
-				ts := NowBeijing() // SG-023: 使用北京时间
+		matcher.Observe(level, content)
 
-				logMsg := LogMessage{
-					ExecutionID: execution.ID,
-					TaskID:      task.ID,
-					Timestamp:   ts,
-					Level:       string(models.LogLevelStdout),
-					Content:     content,
-				}
-				s.logChan <- logMsg
-
-				entry := &models.Log{
-					ExecutionID: execution.ID,
-					TaskID:      task.ID,
-					Timestamp:   ts,
-					Level:       models.LogLevelStdout,
-					Content:     content,
-				}
+		// 脱敏后再推送和落库
+		content = s.redactor.Redact(content)
 
-				select {
-				case logQueue <- entry:
-				default:
-					droppedStdout++
-				}
+		// 结构化日志（JSON / logfmt）：取其级别与消息，其余字段单独存储
+		entryLevel := level
+		var fields models.JSONMap
+		if parsed := parseStructuredLine(content, task.LogFormat); parsed != nil {
+			content = parsed.Message
+			fields = parsed.Fields
+			if parsed.Level != "" {
+				entryLevel = parsed.Level
 			}
+		}
 
-			if errors.Is(readErr, io.EOF) {
-				break
-			}
-			if readErr != nil {
-				log.Printf("读取 stdout 错误(execution_id=%s): %v", execution.ID, readErr)
-				break
+		ts := NowBeijing() // SG-023: 使用北京时间
+
+		logMsg := LogMessage{
+			ExecutionID: execution.ID,
+			TaskID:      task.ID,
+			Timestamp:   ts,
+			Level:       string(entryLevel),
+			Content:     content,
+			Stream:      string(level),
+			Fields:      fields,
+		}
+		s.logChan <- logMsg
+
+		entry := &models.Log{
+			ExecutionID: execution.ID,
+			TaskID:      task.ID,
+			Timestamp:   ts,
+			Level:       entryLevel,
+			Content:     content,
+			Stream:      level,
+			Fields:      fields,
+		}
+
+		select {
+		case logQueue <- entry:
+		default:
+			// 队列已满：暂存到磁盘，暂存失败才计为丢弃
+			if err := spill.Write(entry); err != nil {
+				*dropped++
 			}
 		}
-	}()
+	}
 
-	// 实时读取 stderr（单行最多 1MB，超出部分截断但继续 drain）
-	go func() {
+	// readStream 实时读取输出流（单行最多 1MB，超出部分截断但继续 drain）
+	readStream := func(r io.Reader, level models.LogLevel, dropped *int) {
 		defer wg.Done()
-		reader := bufio.NewReaderSize(stderr, 64*1024)
+		reader := bufio.NewReaderSize(r, 64*1024)
 
 		for {
 			line, truncated, readAny, readErr := readLineWithLimit(reader, maxLogLineBytes)
@@ -239,54 +429,61 @@ func (s *ExecutorService) ExecuteScript(task *models.Task) (*models.Execution, e
 				if truncated {
 					content += " ...(已截断)"
 				}
-
-				ts := NowBeijing() // SG-023: 使用北京时间
-
-				logMsg := LogMessage{
-					ExecutionID: execution.ID,
-					TaskID:      task.ID,
-					Timestamp:   ts,
-					Level:       string(models.LogLevelStderr),
-					Content:     content,
-				}
-				s.logChan <- logMsg
-
-				entry := &models.Log{
-					ExecutionID: execution.ID,
-					TaskID:      task.ID,
-					Timestamp:   ts,
-					Level:       models.LogLevelStderr,
-					Content:     content,
-				}
-
-				select {
-				case logQueue <- entry:
-				default:
-					droppedStderr++
-				}
+				handleLine(level, content, dropped)
 			}
 
 			if errors.Is(readErr, io.EOF) {
 				break
 			}
 			if readErr != nil {
-				log.Printf("读取 stderr 错误(execution_id=%s): %v", execution.ID, readErr)
+				log.Printf("读取 %s 错误(execution_id=%s): %v", level, execution.ID, readErr)
 				break
 			}
 		}
-	}()
+	}
+
+	go readStream(stdout, models.LogLevelStdout, &droppedStdout)
+	go readStream(stderr, models.LogLevelStderr, &droppedStderr)
 
 	// 等待 stdout/stderr 读取完成（确保 pipe 被 drain）
 	wg.Wait()
+	watchdog.Stop()
 
 	// 刷新并结束批量写入
 	close(logQueue)
 	<-writerDone
 
-	// 写入丢弃汇总（避免静默丢日志）
+	// 补录暂存到磁盘的溢出日志（按原始时间戳入库，查询时自然按时间穿插）
+	if spilled := spill.Count(); spilled > 0 {
+		summary := fmt.Sprintf("日志输出过快，%d 行日志曾暂存到磁盘，已在执行结束后补录", spilled)
+		level := models.LogLevelInfo
+		if n, err := spill.Ingest(syncedWrite(sink)); err != nil {
+			log.Printf("补录溢出日志失败(execution_id=%s): %v", execution.ID, err)
+			summary = fmt.Sprintf("日志输出过快，%d 行日志暂存到磁盘后补录失败（已补录 %d 行），完整日志保留在文件: %s",
+				spilled, n, spill.Path())
+			level = models.LogLevelWarning
+		}
+		spillLog := &models.Log{
+			ExecutionID: execution.ID,
+			TaskID:      task.ID,
+			Timestamp:   NowBeijing(),
+			Level:       level,
+			Content:     summary,
+		}
+		if err := database.GetDB().Create(spillLog).Error; err != nil {
+			log.Printf("保存日志补录汇总失败(execution_id=%s): %v", execution.ID, err)
+		}
+	}
+
+	// 关闭日志存储（文件后端写入剩余缓冲）
+	if err := sink.Close(); err != nil {
+		log.Printf("关闭日志存储失败(execution_id=%s): %v", execution.ID, err)
+	}
+
+	// 写入丢弃汇总（仅在磁盘暂存也失败时出现，避免静默丢日志）
 	if droppedStdout > 0 || droppedStderr > 0 {
 		summary := fmt.Sprintf(
-			"日志过多已丢弃：stdout=%d 行，stderr=%d 行（为避免阻塞执行，系统已自动丢弃部分日志）",
+			"日志过多已丢弃：stdout=%d 行，stderr=%d 行（写入队列已满且暂存到磁盘失败）",
 			droppedStdout, droppedStderr,
 		)
 
@@ -304,26 +501,82 @@ func (s *ExecutorService) ExecuteScript(task *models.Task) (*models.Execution, e
 
 	// 等待执行完成
 	err = cmd.Wait()
+	monitor.Stop(execution, cmd.ProcessState)
 	now := NowBeijing() // SG-023: 使用北京时间
 	execution.EndTime = &now
 	execution.DurationMs = now.Sub(execution.StartTime).Milliseconds()
+	if values, readErr := readOutputFile(outputFile); readErr != nil {
+		log.Printf("读取输出文件失败(execution_id=%s): %v", execution.ID, readErr)
+		s.SaveInfoLog(execution.ID, task.ID, "读取输出文件失败: "+readErr.Error())
+	} else if len(values) > 0 {
+		report.MergeOutputs(values)
+	}
+	report.ApplyTo(execution)
+
+	timedOut := s.timeout > 0 && execution.DurationMs >= s.timeout.Milliseconds()
+	execution.StalledAt = watchdog.StalledAt()
+
+	// 非零退出码在任务允许列表中时视为正常结束
+	var exitErr *exec.ExitError
+	memoryKilled := err != nil && monitor.MemoryLimitHit()
+	if errors.As(err, &exitErr) && !timedOut && !watchdog.Killed() && !memoryKilled && matcher.ExitCodeAllowed(exitErr.ExitCode()) {
+		execution.ExitCode = exitErr.ExitCode()
+		err = nil
+	}
 
 	if err != nil {
 		execution.Status = models.StatusFailed
 		if cmd.ProcessState != nil {
 			execution.ExitCode = cmd.ProcessState.ExitCode()
 		}
-		// 检查是否超时
-		if s.timeout > 0 && execution.DurationMs >= s.timeout.Milliseconds() {
+		// 检查是否启动失败、超时、超出内存限制或因卡住被终止
+		if resumeErr != nil {
+			execution.ErrorMessage = "启动进程失败: " + resumeErr.Error()
+		} else if memoryKilled {
+			execution.ErrorMessage = fmt.Sprintf("已终止：超出内存限制（%d MB）: %v", task.MaxMemoryMB, err)
+		} else if timedOut {
+			execution.TimedOut = true
 			execution.ErrorMessage = "执行超时: " + err.Error()
+		} else if watchdog.Killed() {
+			execution.ErrorMessage = fmt.Sprintf("连续 %d 分钟无输出，已终止: %v", task.StallMinutes, err)
 		} else {
 			execution.ErrorMessage = err.Error()
 		}
+		execution.ErrorMessage = s.redactor.Redact(execution.ErrorMessage)
+	} else if !matcher.ExitCodeAllowed(execution.ExitCode) {
+		// 退出码 0 但不在允许列表中
+		execution.Status = models.StatusFailed
+		execution.ErrorMessage = fmt.Sprintf("退出码 %d 不在允许列表中", execution.ExitCode)
+		err = errors.New(execution.ErrorMessage)
 	} else {
-		execution.Status = models.StatusSuccess
-		execution.ExitCode = 0
+		// 按输出规则与产出文件判定最终结果
+		status, reasons := matcher.Evaluate(task, execution)
+		execution.Status = status
+		if len(reasons) > 0 {
+			execution.ErrorMessage = s.redactor.Redact(strings.Join(reasons, "；"))
+		}
+		if status == models.StatusFailed {
+			err = fmt.Errorf("未满足成功判定规则: %s", execution.ErrorMessage)
+		}
+	}
+
+	// 收集执行产物（失败的执行也收集，便于排查）
+	artifacts, artifactErr := s.captureArtifacts(task, execution)
+	if artifactErr != nil {
+		log.Printf("收集执行产物失败(execution_id=%s): %v", execution.ID, artifactErr)
+		s.SaveInfoLog(execution.ID, task.ID, "收集执行产物失败: "+artifactErr.Error())
+	}
+	if len(artifacts) > 0 {
+		s.SaveInfoLog(execution.ID, task.ID, fmt.Sprintf("已收集 %d 个执行产物", len(artifacts)))
 	}
 
+	s.logChan <- LogMessage{
+		ExecutionID: execution.ID,
+		TaskID:      task.ID,
+		Timestamp:   NowBeijing(),
+		Level:       StreamEventFinished,
+		Content:     string(execution.Status),
+	}
 	return execution, err
 }
 
@@ -348,12 +601,17 @@ func (s *ExecutorService) saveLog(logMsg *LogMessage) {
 
 // SaveInfoLog 保存信息日志
 func (s *ExecutorService) SaveInfoLog(executionID, taskID, content string) {
+	s.saveSystemLog(executionID, taskID, models.LogLevelInfo, content)
+}
+
+// saveSystemLog 保存并推送一条系统日志
+func (s *ExecutorService) saveSystemLog(executionID, taskID string, level models.LogLevel, content string) {
 	logMsg := &LogMessage{
 		ExecutionID: executionID,
 		TaskID:      taskID,
 		Timestamp:   NowBeijing(), // SG-023: 使用北京时间
-		Level:       string(models.LogLevelInfo),
-		Content:     content,
+		Level:       string(level),
+		Content:     s.redactor.Redact(content),
 	}
 	s.logChan <- *logMsg
 	s.saveLog(logMsg)
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"os"
+	"scriptguard/backend/models"
+	"strings"
+	"sync"
+)
+
+// 失败上下文参数
+const (
+	failureScanLines  = 200 // 查找 stderr 与 traceback 时读取的最近日志行数
+	maxTracebackLines = 40  // 超出时保留首行与最后的调用栈
+)
+
+// pythonTracebackHeader Python 未捕获异常输出的首行
+const pythonTracebackHeader = "Traceback (most recent call last):"
+
+// hostName 本机主机名，告警中用于区分多台机器上的 ScriptGuard
+var hostName = sync.OnceValue(func() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return name
+})
+
+// failureContext 读取失败执行最近的 stderr 输出，并提取其中最后一段 Python traceback
+// excerpt 为 traceback 之前的最后几行 stderr；执行没有 stderr 输出时取全部输出的最后几行
+func failureContext(executionID string) (excerpt, traceback string) {
+	logs, err := QueryRecentLogs(executionID, "", failureScanLines)
+	if err != nil {
+		return "", ""
+	}
+
+	// 结构化日志的 level 为日志自身的级别，按来源输出流筛选
+	var lines []string
+	start := -1
+	for _, entry := range logs {
+		stream := entry.Stream
+		if stream == "" {
+			stream = entry.Level
+		}
+		if stream != models.LogLevelStderr {
+			continue
+		}
+		if strings.Contains(entry.Content, pythonTracebackHeader) {
+			start = len(lines)
+		}
+		lines = append(lines, truncateRunes(entry.Content, notifyExcerptLineSize))
+	}
+	if len(lines) == 0 {
+		return logExcerpt(executionID), ""
+	}
+
+	before := lines
+	if start >= 0 {
+		tb := lines[start:]
+		if len(tb) > maxTracebackLines {
+			tb = append([]string{tb[0], "..."}, tb[len(tb)-maxTracebackLines+2:]...)
+		}
+		traceback = strings.Join(tb, "\n")
+		before = lines[:start]
+	}
+	if len(before) > notifyExcerptLines {
+		before = before[len(before)-notifyExcerptLines:]
+	}
+	return strings.Join(before, "\n"), traceback
+}
+
+// fitNotification 将通知裁剪到渠道的消息长度上限（按字节计）以内
+// 依次缩短 traceback 与日志摘录（保留最后几行）、Markdown 正文（保留开头几行），仍超出时截断较长的字段与说明
+func fitNotification(n *Notification, maxBytes int) *Notification {
+	over := notificationSize(n) - maxBytes
+	if maxBytes <= 0 || over <= 0 {
+		return n
+	}
+	out := *n
+	out.Fields = append([]NotificationField(nil), n.Fields...)
+
+	out.Traceback, over = trimHeadLines(out.Traceback, over)
+	out.Excerpt, over = trimHeadLines(out.Excerpt, over)
+	out.Markdown, over = trimTailLines(out.Markdown, over)
+	if over <= 0 {
+		return &out
+	}
+	for i := range out.Fields {
+		out.Fields[i].Value = truncateRunes(out.Fields[i].Value, notifyExcerptLineSize)
+	}
+	out.Note = truncateRunes(out.Note, notifyExcerptLineSize)
+	return &out
+}
+
+// notificationSize 通知各部分的总字节数（不含渠道自身的格式开销）
+func notificationSize(n *Notification) int {
+	return len(n.Text()) + len(n.Excerpt) + len(n.Traceback)
+}
+
+// trimTailLines 从末尾整行删除并以省略行结尾，至少减少 over 字节；返回剩余内容与仍需减少的字节数
+func trimTailLines(s string, over int) (string, int) {
+	const ellipsis = "\n..."
+	if over <= 0 || s == "" {
+		return s, over
+	}
+	over += len(ellipsis)
+	for over > 0 && s != "" {
+		i := strings.LastIndexByte(s, '\n')
+		if i < 0 {
+			return "", over - len(s) - len(ellipsis)
+		}
+		over -= len(s) - i
+		s = s[:i]
+	}
+	return s + ellipsis, over
+}
+
+// trimHeadLines 从开头整行删除，至少减少 over 字节；返回剩余内容与仍需减少的字节数
+func trimHeadLines(s string, over int) (string, int) {
+	for over > 0 && s != "" {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			return "", over - len(s)
+		}
+		s = s[i+1:]
+		over -= i + 1
+	}
+	return s, over
+}
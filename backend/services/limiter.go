@@ -1,15 +1,82 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"scriptguard/backend/models"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 )
 
+// ErrAlreadyQueued 同一任务已有排队中的执行
+var ErrAlreadyQueued = errors.New("该任务已有排队中的执行")
+
+// AcquireRequest 一次执行的资源申请
+type AcquireRequest struct {
+	TaskID   string
+	TaskName string
+	Priority int      // 越大越优先
+	Weight   int      // 占用的并发槽位数，<1 按 1 计
+	Pools    []string // 所属资源池，每个资源池占用 1 个名额
+}
+
+func (r AcquireRequest) weight() int {
+	return max(r.Weight, 1)
+}
+
+// QueuedRun 排队中的执行（按授予顺序）
+type QueuedRun struct {
+	Position   int       `json:"position"` // 从 1 开始
+	TaskID     string    `json:"task_id"`
+	TaskName   string    `json:"task_name"`
+	Priority   int       `json:"priority"`
+	Weight     int       `json:"weight"`
+	Pools      []string  `json:"pools"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// maxTaskPriority 任务优先级的绝对值上限
+const maxTaskPriority = 100
+
+// ValidateQueueSettings 校验任务的排队优先级与权重（权重不能超过当前最大并发数）
+func ValidateQueueSettings(task *models.Task, maxConcurrency int) error {
+	switch {
+	case task.Priority < -maxTaskPriority || task.Priority > maxTaskPriority:
+		return fmt.Errorf("优先级超出允许范围：%d~%d", -maxTaskPriority, maxTaskPriority)
+	case task.Weight < 0:
+		return fmt.Errorf("权重不能为负数")
+	case task.Weight > maxConcurrency:
+		return fmt.Errorf("任务权重 %d 超过最大并发数 %d", task.Weight, maxConcurrency)
+	}
+	return nil
+}
+
+// limiterWaiter 排队中的申请
+type limiterWaiter struct {
+	req        AcquireRequest
+	seq        uint64
+	enqueuedAt time.Time
+	done       chan error // 授予时写入 nil，被拒绝时写入原因
+}
+
+// blockedGlobal 表示申请因全局槽位不足而无法授予
+const blockedGlobal = "*"
+
 // ConcurrencyLimiter 并发限制器
+// 每次执行按权重占用全局槽位，并在所属的每个资源池各占 1 个名额；全局槽位与全部资源池在同一把锁下
+// 一次性获取，不会出现持有部分资源等待其他资源的死锁。
+// 排队的执行按优先级（高优先）、再按排队先后授予：全局槽位不足时不越过队首授予后面的执行，
+// 避免大权重任务被持续饿死；仅因资源池满而等待的执行不阻塞与其无关的任务
 type ConcurrencyLimiter struct {
-	mu      sync.Mutex
-	running int
-	max     int
-	cond    *sync.Cond
+	mu          sync.Mutex
+	running     int // 已占用槽位
+	max         int
+	poolLimits  map[string]int // 资源池名额上限（未配置的资源池不限制）
+	poolRunning map[string]int // 资源池已占用名额
+	queue       []*limiterWaiter
+	seq         uint64
 }
 
 // NewConcurrencyLimiter 创建并发限制器
@@ -17,39 +84,143 @@ func NewConcurrencyLimiter(max int) *ConcurrencyLimiter {
 	if max < 1 {
 		max = 1
 	}
-	l := &ConcurrencyLimiter{max: max}
-	l.cond = sync.NewCond(&l.mu)
-	return l
+	return &ConcurrencyLimiter{
+		max:         max,
+		poolLimits:  make(map[string]int),
+		poolRunning: make(map[string]int),
+	}
+}
+
+// blockedBy 申请当前无法授予的原因：空串表示可以授予，blockedGlobal 表示全局槽位不足，否则为已满的资源池名
+// 调用方需持有锁
+func (l *ConcurrencyLimiter) blockedBy(req AcquireRequest) string {
+	if l.running+req.weight() > l.max {
+		return blockedGlobal
+	}
+	for _, name := range req.Pools {
+		if limit, ok := l.poolLimits[name]; ok && l.poolRunning[name] >= limit {
+			return name
+		}
+	}
+	return ""
+}
+
+// grant 占用申请所需的全部资源（调用方需持有锁）
+func (l *ConcurrencyLimiter) grant(req AcquireRequest) {
+	l.running += req.weight()
+	for _, name := range req.Pools {
+		l.poolRunning[name]++
+	}
 }
 
 // TryAcquire 尝试获取执行权限（非阻塞）
-// 返回 true 表示获取成功，false 表示已达上限
-func (l *ConcurrencyLimiter) TryAcquire() bool {
+// 返回 true 表示获取成功；资源不足，或有同等及更高优先级的执行在等待全局槽位或同一资源池时返回 false
+func (l *ConcurrencyLimiter) TryAcquire(req AcquireRequest) bool {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	if l.running >= l.max {
+	if l.blockedBy(req) != "" {
 		return false
 	}
-	l.running++
+	for _, w := range l.queue {
+		if w.req.Priority < req.Priority {
+			continue
+		}
+		if l.blockedBy(w.req) == blockedGlobal || sharesPool(w.req.Pools, req.Pools) {
+			return false
+		}
+	}
+	l.grant(req)
 	return true
 }
 
-// Acquire 获取执行权限（阻塞等待）
-func (l *ConcurrencyLimiter) Acquire() {
+// Acquire 获取执行权限（阻塞排队）
+// 权重超过最大并发数的执行永远无法满足，直接拒绝；同一任务只保留一个排队中的执行，重复排队返回 ErrAlreadyQueued
+func (l *ConcurrencyLimiter) Acquire(req AcquireRequest) error {
 	l.mu.Lock()
-	defer l.mu.Unlock()
-	for l.running >= l.max {
-		l.cond.Wait()
+	if req.weight() > l.max {
+		l.mu.Unlock()
+		return fmt.Errorf("任务权重 %d 超过最大并发数 %d", req.weight(), l.max)
 	}
-	l.running++
+	for _, w := range l.queue {
+		if w.req.TaskID == req.TaskID {
+			l.mu.Unlock()
+			return ErrAlreadyQueued
+		}
+	}
+	l.seq++
+	w := &limiterWaiter{
+		req:        req,
+		seq:        l.seq,
+		enqueuedAt: NowBeijing(),
+		done:       make(chan error, 1),
+	}
+	l.queue = append(l.queue, w)
+	sort.SliceStable(l.queue, func(i, j int) bool {
+		a, b := l.queue[i], l.queue[j]
+		if a.req.Priority != b.req.Priority {
+			return a.req.Priority > b.req.Priority
+		}
+		return a.seq < b.seq
+	})
+	l.dispatch()
+	l.mu.Unlock()
+
+	return <-w.done
+}
+
+// dispatch 按队列顺序授予（调用方需持有锁）
+// 出现等待全局槽位的执行后不再授予后续执行；等待资源池的执行只阻塞排在其后、与其共用资源池的执行
+func (l *ConcurrencyLimiter) dispatch() {
+	var (
+		remaining     []*limiterWaiter
+		globalBlocked bool
+		blockedPools  []string
+	)
+	for _, w := range l.queue {
+		if w.req.weight() > l.max {
+			// 最大并发数被调小后已无法满足
+			w.done <- fmt.Errorf("任务权重 %d 超过最大并发数 %d", w.req.weight(), l.max)
+			continue
+		}
+		if !globalBlocked && !sharesPool(w.req.Pools, blockedPools) {
+			switch l.blockedBy(w.req) {
+			case "":
+				l.grant(w.req)
+				w.done <- nil
+				continue
+			case blockedGlobal:
+				globalBlocked = true
+			}
+		}
+		remaining = append(remaining, w)
+		blockedPools = append(blockedPools, w.req.Pools...)
+	}
+	l.queue = remaining
+}
+
+// sharesPool 两组资源池是否有交集
+func sharesPool(a, b []string) bool {
+	for _, name := range a {
+		if slices.Contains(b, name) {
+			return true
+		}
+	}
+	return false
 }
 
-// Release 释放执行权限
-func (l *ConcurrencyLimiter) Release() {
+// Release 释放执行权限（req 需与获取时一致）
+func (l *ConcurrencyLimiter) Release(req AcquireRequest) {
 	l.mu.Lock()
-	l.running--
-	l.mu.Unlock()
-	l.cond.Signal()
+	defer l.mu.Unlock()
+	l.running = max(l.running-req.weight(), 0)
+	for _, name := range req.Pools {
+		if l.poolRunning[name] <= 1 {
+			delete(l.poolRunning, name)
+		} else {
+			l.poolRunning[name]--
+		}
+	}
+	l.dispatch()
 }
 
 // SetMax 动态设置最大并发数
@@ -58,12 +229,49 @@ func (l *ConcurrencyLimiter) SetMax(max int) {
 		max = 1
 	}
 	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.max = max
-	l.mu.Unlock()
-	l.cond.Broadcast()
+	l.dispatch()
+}
+
+// SetPools 设置资源池名额上限（整体替换；运行中的执行保留已占用的名额）
+func (l *ConcurrencyLimiter) SetPools(limits map[string]int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.poolLimits = make(map[string]int, len(limits))
+	for name, limit := range limits {
+		l.poolLimits[name] = max(limit, 1)
+	}
+	l.dispatch()
+}
+
+// Queued 排队中的执行（按授予顺序）
+func (l *ConcurrencyLimiter) Queued() []QueuedRun {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	runs := make([]QueuedRun, 0, len(l.queue))
+	for i, w := range l.queue {
+		runs = append(runs, QueuedRun{
+			Position:   i + 1,
+			TaskID:     w.req.TaskID,
+			TaskName:   w.req.TaskName,
+			Priority:   w.req.Priority,
+			Weight:     w.req.weight(),
+			Pools:      slices.Clone(w.req.Pools),
+			EnqueuedAt: w.enqueuedAt,
+		})
+	}
+	return runs
+}
+
+// PoolUsage 资源池已占用名额
+func (l *ConcurrencyLimiter) PoolUsage(name string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.poolRunning[name]
 }
 
-// GetRunning 获取当前运行数
+// GetRunning 获取当前已占用槽位
 func (l *ConcurrencyLimiter) GetRunning() int {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+// limiterWaitTimeout 等待排队状态变化的上限
+const limiterWaitTimeout = 2 * time.Second
+
+// waitUntil 轮询直到条件成立，超时则测试失败
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(limiterWaitTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// queuedIDs 排队中的任务 ID（按授予顺序）
+func queuedIDs(l *ConcurrencyLimiter) []string {
+	var ids []string
+	for _, run := range l.Queued() {
+		ids = append(ids, run.TaskID)
+	}
+	return ids
+}
+
+func TestConcurrencyLimiterTryAcquire(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int
+		pools   map[string]int
+		holding []AcquireRequest
+		req     AcquireRequest
+		want    bool
+	}{
+		{
+			name: "空闲时获取",
+			max:  2,
+			req:  AcquireRequest{TaskID: "a"},
+			want: true,
+		},
+		{
+			name:    "剩余槽位足够",
+			max:     3,
+			holding: []AcquireRequest{{TaskID: "a", Weight: 1}},
+			req:     AcquireRequest{TaskID: "b", Weight: 2},
+			want:    true,
+		},
+		{
+			name:    "权重超过剩余槽位",
+			max:     3,
+			holding: []AcquireRequest{{TaskID: "a", Weight: 2}},
+			req:     AcquireRequest{TaskID: "b", Weight: 2},
+			want:    false,
+		},
+		{
+			name: "权重超过最大并发数",
+			max:  2,
+			req:  AcquireRequest{TaskID: "a", Weight: 3},
+			want: false,
+		},
+		{
+			name:    "权重小于 1 按 1 计",
+			max:     2,
+			holding: []AcquireRequest{{TaskID: "a", Weight: 0}},
+			req:     AcquireRequest{TaskID: "b", Weight: -1},
+			want:    true,
+		},
+		{
+			name:    "资源池已满",
+			max:     5,
+			pools:   map[string]int{"db": 1},
+			holding: []AcquireRequest{{TaskID: "a", Pools: []string{"db"}}},
+			req:     AcquireRequest{TaskID: "b", Pools: []string{"db"}},
+			want:    false,
+		},
+		{
+			name:    "其他资源池不受影响",
+			max:     5,
+			pools:   map[string]int{"db": 1, "gpu": 1},
+			holding: []AcquireRequest{{TaskID: "a", Pools: []string{"db"}}},
+			req:     AcquireRequest{TaskID: "b", Pools: []string{"gpu"}},
+			want:    true,
+		},
+		{
+			name:    "多个资源池需全部有空余",
+			max:     5,
+			pools:   map[string]int{"db": 1, "gpu": 2},
+			holding: []AcquireRequest{{TaskID: "a", Pools: []string{"db"}}},
+			req:     AcquireRequest{TaskID: "b", Pools: []string{"gpu", "db"}},
+			want:    false,
+		},
+		{
+			name:    "未配置的资源池不限制",
+			max:     5,
+			holding: []AcquireRequest{{TaskID: "a", Pools: []string{"adhoc"}}},
+			req:     AcquireRequest{TaskID: "b", Pools: []string{"adhoc"}},
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewConcurrencyLimiter(tt.max)
+			l.SetPools(tt.pools)
+			for _, req := range tt.holding {
+				if !l.TryAcquire(req) {
+					t.Fatalf("初始占用失败: %s", req.TaskID)
+				}
+			}
+			if got := l.TryAcquire(tt.req); got != tt.want {
+				t.Errorf("TryAcquire() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimiterTryAcquireRespectsQueue(t *testing.T) {
+	tests := []struct {
+		name   string
+		queued AcquireRequest
+		req    AcquireRequest
+		want   bool
+	}{
+		{
+			name:   "不越过等待全局槽位的同优先级执行",
+			queued: AcquireRequest{TaskID: "big", Weight: 2},
+			req:    AcquireRequest{TaskID: "small"},
+			want:   false,
+		},
+		{
+			name:   "更高优先级可越过",
+			queued: AcquireRequest{TaskID: "big", Weight: 2},
+			req:    AcquireRequest{TaskID: "urgent", Priority: 10},
+			want:   true,
+		},
+		{
+			name:   "等待资源池的执行不阻塞无关任务",
+			queued: AcquireRequest{TaskID: "q", Pools: []string{"db"}},
+			req:    AcquireRequest{TaskID: "r"},
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewConcurrencyLimiter(2)
+			l.SetPools(map[string]int{"db": 1})
+			holding := AcquireRequest{TaskID: "holding", Pools: []string{"db"}}
+			if !l.TryAcquire(holding) {
+				t.Fatal("初始占用失败")
+			}
+			defer l.Release(holding)
+
+			go l.Acquire(tt.queued)
+			waitUntil(t, "进入排队", func() bool { return len(l.Queued()) == 1 })
+
+			if got := l.TryAcquire(tt.req); got != tt.want {
+				t.Errorf("TryAcquire() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimiterDispatchOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		max       int
+		pools     map[string]int
+		holding   AcquireRequest   // 初始占用，排队完成后释放
+		queued    []AcquireRequest // 按先后排队
+		wantQueue []string         // 排队后的授予顺序
+		immediate []string         // 排队时即获得槽位的执行
+		order     []string         // 释放初始占用后依次获得槽位（每次获得后随即释放）
+	}{
+		{
+			name:    "先进先出",
+			max:     1,
+			holding: AcquireRequest{TaskID: "holding"},
+			queued: []AcquireRequest{
+				{TaskID: "a"}, {TaskID: "b"}, {TaskID: "c"},
+			},
+			wantQueue: []string{"a", "b", "c"},
+			order:     []string{"a", "b", "c"},
+		},
+		{
+			name:    "高优先级先授予，同优先级按先后",
+			max:     1,
+			holding: AcquireRequest{TaskID: "holding"},
+			queued: []AcquireRequest{
+				{TaskID: "low", Priority: -5},
+				{TaskID: "normal1"},
+				{TaskID: "high", Priority: 5},
+				{TaskID: "normal2"},
+			},
+			wantQueue: []string{"high", "normal1", "normal2", "low"},
+			order:     []string{"high", "normal1", "normal2", "low"},
+		},
+		{
+			name:    "大权重任务不被后来的小任务饿死",
+			max:     3,
+			holding: AcquireRequest{TaskID: "holding", Weight: 2},
+			queued: []AcquireRequest{
+				{TaskID: "big", Weight: 3},
+				{TaskID: "small"},
+			},
+			wantQueue: []string{"big", "small"},
+			order:     []string{"big", "small"},
+		},
+		{
+			name:    "等待资源池的执行不阻塞无关任务",
+			max:     3,
+			pools:   map[string]int{"db": 1},
+			holding: AcquireRequest{TaskID: "holding", Pools: []string{"db"}},
+			queued: []AcquireRequest{
+				{TaskID: "db1", Pools: []string{"db"}},
+				{TaskID: "free"},
+				{TaskID: "db2", Pools: []string{"db"}},
+			},
+			wantQueue: []string{"db1", "db2"},
+			immediate: []string{"free"},
+			order:     []string{"db1", "db2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewConcurrencyLimiter(tt.max)
+			l.SetPools(tt.pools)
+			if !l.TryAcquire(tt.holding) {
+				t.Fatal("初始占用失败")
+			}
+
+			requests := make(map[string]AcquireRequest)
+			granted := make(chan string, len(tt.queued))
+			for i, req := range tt.queued {
+				requests[req.TaskID] = req
+				go func(req AcquireRequest) {
+					if err := l.Acquire(req); err != nil {
+						t.Errorf("Acquire(%s) 失败: %v", req.TaskID, err)
+						return
+					}
+					granted <- req.TaskID
+				}(req)
+				// 逐个排队，保证排队先后确定
+				waitUntil(t, "进入排队或获得槽位", func() bool {
+					return len(l.Queued())+len(granted) == i+1
+				})
+			}
+
+			if got := queuedIDs(l); !slices.Equal(got, tt.wantQueue) {
+				t.Fatalf("排队顺序 = %v, want %v", got, tt.wantQueue)
+			}
+			var immediate []string
+			for len(granted) > 0 {
+				immediate = append(immediate, <-granted)
+			}
+			sort.Strings(immediate)
+			if !slices.Equal(immediate, tt.immediate) {
+				t.Fatalf("立即获得槽位 = %v, want %v", immediate, tt.immediate)
+			}
+
+			l.Release(tt.holding)
+			for _, want := range tt.order {
+				select {
+				case got := <-granted:
+					if got != want {
+						t.Fatalf("授予顺序错误: got %s, want %s", got, want)
+					}
+					l.Release(requests[got])
+				case <-time.After(limiterWaitTimeout):
+					t.Fatalf("等待 %s 获得槽位超时", want)
+				}
+			}
+			if n := len(l.Queued()); n != 0 {
+				t.Errorf("仍有 %d 个执行在排队", n)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimiterAccounting(t *testing.T) {
+	type step struct {
+		release bool
+		req     AcquireRequest
+	}
+	tests := []struct {
+		name        string
+		steps       []step
+		wantRunning int
+		wantPools   map[string]int
+	}{
+		{
+			name: "按权重占用",
+			steps: []step{
+				{req: AcquireRequest{TaskID: "a", Weight: 3}},
+				{req: AcquireRequest{TaskID: "b"}},
+			},
+			wantRunning: 4,
+		},
+		{
+			name: "释放归还同等权重",
+			steps: []step{
+				{req: AcquireRequest{TaskID: "a", Weight: 3}},
+				{req: AcquireRequest{TaskID: "b", Weight: 2}},
+				{release: true, req: AcquireRequest{TaskID: "a", Weight: 3}},
+			},
+			wantRunning: 2,
+		},
+		{
+			name: "资源池每次执行占 1 个名额，与权重无关",
+			steps: []step{
+				{req: AcquireRequest{TaskID: "a", Weight: 3, Pools: []string{"db", "gpu"}}},
+				{req: AcquireRequest{TaskID: "b", Pools: []string{"db"}}},
+			},
+			wantRunning: 4,
+			wantPools:   map[string]int{"db": 2, "gpu": 1},
+		},
+		{
+			name: "全部释放后归零",
+			steps: []step{
+				{req: AcquireRequest{TaskID: "a", Weight: 2, Pools: []string{"db"}}},
+				{release: true, req: AcquireRequest{TaskID: "a", Weight: 2, Pools: []string{"db"}}},
+			},
+			wantRunning: 0,
+			wantPools:   map[string]int{"db": 0},
+		},
+		{
+			name: "多余的释放不会出现负数",
+			steps: []step{
+				{release: true, req: AcquireRequest{TaskID: "a", Weight: 2, Pools: []string{"db"}}},
+			},
+			wantRunning: 0,
+			wantPools:   map[string]int{"db": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewConcurrencyLimiter(10)
+			for _, s := range tt.steps {
+				if s.release {
+					l.Release(s.req)
+				} else if !l.TryAcquire(s.req) {
+					t.Fatalf("获取失败: %s", s.req.TaskID)
+				}
+			}
+			if got := l.GetRunning(); got != tt.wantRunning {
+				t.Errorf("GetRunning() = %d, want %d", got, tt.wantRunning)
+			}
+			for name, want := range tt.wantPools {
+				if got := l.PoolUsage(name); got != want {
+					t.Errorf("PoolUsage(%s) = %d, want %d", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimiterRejects(t *testing.T) {
+	t.Run("权重超过最大并发数", func(t *testing.T) {
+		l := NewConcurrencyLimiter(2)
+		if err := l.Acquire(AcquireRequest{TaskID: "a", Weight: 3}); err == nil {
+			t.Fatal("Acquire() 应返回错误")
+		}
+	})
+
+	t.Run("同一任务重复排队", func(t *testing.T) {
+		l := NewConcurrencyLimiter(1)
+		holding := AcquireRequest{TaskID: "holding"}
+		l.TryAcquire(holding)
+		go l.Acquire(AcquireRequest{TaskID: "a"})
+		waitUntil(t, "进入排队", func() bool { return len(l.Queued()) == 1 })
+
+		if err := l.Acquire(AcquireRequest{TaskID: "a"}); !errors.Is(err, ErrAlreadyQueued) {
+			t.Fatalf("Acquire() = %v, want ErrAlreadyQueued", err)
+		}
+		l.Release(holding)
+	})
+
+	t.Run("调小最大并发数后拒绝无法满足的排队", func(t *testing.T) {
+		l := NewConcurrencyLimiter(3)
+		holding := AcquireRequest{TaskID: "holding", Weight: 3}
+		l.TryAcquire(holding)
+		result := make(chan error, 1)
+		go func() { result <- l.Acquire(AcquireRequest{TaskID: "big", Weight: 3}) }()
+		waitUntil(t, "进入排队", func() bool { return len(l.Queued()) == 1 })
+
+		l.SetMax(2)
+		select {
+		case err := <-result:
+			if err == nil {
+				t.Fatal("Acquire() 应返回错误")
+			}
+		case <-time.After(limiterWaitTimeout):
+			t.Fatal("排队的执行未被拒绝")
+		}
+		if n := len(l.Queued()); n != 0 {
+			t.Errorf("仍有 %d 个执行在排队", n)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"scriptguard/backend/models"
+	"strconv"
+	"strings"
+)
+
+// 结构化日志中表示级别与消息的常见字段名（按优先级）
+var (
+	structuredLevelKeys   = []string{"level", "levelname", "severity", "lvl", "log.level"}
+	structuredMessageKeys = []string{"message", "msg", "event"}
+)
+
+// structuredLine 结构化日志行的解析结果
+type structuredLine struct {
+	Level   models.LogLevel // 无法识别时为空
+	Message string
+	Fields  models.JSONMap
+}
+
+// parseStructuredLine 按任务输出格式解析一行输出，不是结构化日志时返回 nil
+func parseStructuredLine(line string, format string) *structuredLine {
+	if format == models.LogFormatPlain {
+		return nil
+	}
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}") {
+		return parseJSONLine(trimmed)
+	}
+	if format == models.LogFormatLogfmt {
+		return parseLogfmtLine(trimmed)
+	}
+	return nil
+}
+
+// parseJSONLine 解析 JSON 对象行
+func parseJSONLine(line string) *structuredLine {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	var obj map[string]any
+	if err := decoder.Decode(&obj); err != nil || decoder.More() {
+		return nil
+	}
+	return newStructuredLine(obj, line)
+}
+
+// parseLogfmtLine 解析 logfmt 行
+// 至少包含级别或消息字段才视为 logfmt，避免把普通的 "a=1 b=2" 输出误判
+func parseLogfmtLine(line string) *structuredLine {
+	pairs, ok := parseKeyValues(line)
+	if !ok {
+		return nil
+	}
+	obj := make(map[string]any, len(pairs))
+	for key, value := range pairs {
+		obj[key] = value
+	}
+	if !hasAnyKey(obj, structuredLevelKeys) && !hasAnyKey(obj, structuredMessageKeys) {
+		return nil
+	}
+	return newStructuredLine(obj, line)
+}
+
+// parseKeyValues 解析 key=value 空格分隔的键值对，值可用双引号包裹
+func parseKeyValues(line string) (map[string]string, bool) {
+	pairs := make(map[string]string)
+	for rest := line; rest != ""; {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			break
+		}
+		eq := strings.IndexByte(rest, '=')
+		space := strings.IndexAny(rest, " \t")
+		if eq <= 0 || (space >= 0 && space < eq) {
+			return nil, false
+		}
+		key := rest[:eq]
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := 1
+			for end < len(rest) && (rest[end] != '"' || rest[end-1] == '\\') {
+				end++
+			}
+			if end >= len(rest) {
+				return nil, false
+			}
+			unquoted, err := strconv.Unquote(rest[:end+1])
+			if err != nil {
+				return nil, false
+			}
+			value = unquoted
+			rest = rest[end+1:]
+		} else if space := strings.IndexAny(rest, " \t"); space >= 0 {
+			value = rest[:space]
+			rest = rest[space:]
+		} else {
+			value = rest
+			rest = ""
+		}
+		pairs[key] = value
+	}
+	return pairs, len(pairs) > 0
+}
+
+// newStructuredLine 提取级别与消息，其余字段原样保留
+func newStructuredLine(obj map[string]any, raw string) *structuredLine {
+	result := &structuredLine{Message: raw}
+
+	for _, key := range structuredLevelKeys {
+		if v, ok := obj[key]; ok {
+			if level := normalizeLogLevel(v); level != "" {
+				result.Level = level
+				delete(obj, key)
+				break
+			}
+		}
+	}
+	for _, key := range structuredMessageKeys {
+		if v, ok := obj[key]; ok {
+			if msg, ok := v.(string); ok {
+				result.Message = msg
+				delete(obj, key)
+				break
+			}
+		}
+	}
+	// python logging 的 levelno 与 levelname 重复，已识别级别时去掉
+	if result.Level != "" {
+		delete(obj, "levelno")
+	}
+
+	if len(obj) > 0 {
+		result.Fields = models.JSONMap(obj)
+	}
+	return result
+}
+
+func hasAnyKey(obj map[string]any, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := obj[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeLogLevel 将常见日志级别名称/数值映射为统一级别，无法识别返回空
+func normalizeLogLevel(v any) models.LogLevel {
+	switch val := v.(type) {
+	case string:
+		switch strings.ToLower(strings.TrimSpace(val)) {
+		case "trace", "debug", "dbug":
+			return models.LogLevelDebug
+		case "info", "information", "notice":
+			return models.LogLevelInfo
+		case "warn", "warning":
+			return models.LogLevelWarning
+		case "error", "err", "critical", "crit", "fatal", "panic", "alert", "emerg", "emergency", "exception":
+			return models.LogLevelError
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(val)); err == nil {
+			return levelFromNumber(n)
+		}
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return levelFromNumber(int(n))
+		}
+	}
+	return ""
+}
+
+// levelFromNumber 按 python logging 的数值级别映射（DEBUG=10 … CRITICAL=50）
+func levelFromNumber(n int) models.LogLevel {
+	switch {
+	case n <= 0:
+		return ""
+	case n < 20:
+		return models.LogLevelDebug
+	case n < 30:
+		return models.LogLevelInfo
+	case n < 40:
+		return models.LogLevelWarning
+	default:
+		return models.LogLevelError
+	}
+}
+
+// logFieldString 字段值的字符串形式（与 SQLite CAST(json_extract(...) AS TEXT) 一致）
+func logFieldString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case json.Number:
+		return val.String()
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	default:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(val); err != nil {
+			return ""
+		}
+		return strings.TrimRight(buf.String(), "\n")
+	}
+}
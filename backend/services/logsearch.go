@@ -0,0 +1,190 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"scriptguard/backend/database"
+	"scriptguard/backend/models"
+	"strings"
+	"unicode/utf8"
+)
+
+// 日志搜索常量
+const (
+	logSearchPageSize    = 100 // 每页命中数
+	logSearchMinTermLen  = 3   // trigram 分词下可走索引的最短检索词（字符数）
+	logSearchSnippetSize = 160 // 降级检索时高亮片段的最大字节数
+	markOpen             = "\x02"
+	markClose            = "\x03"
+)
+
+// LogSearchQuery 日志搜索条件
+type LogSearchQuery struct {
+	Query     string
+	TaskIDs   []string
+	Levels    []string
+	TimeRange models.TimeRange
+	Cursor    string
+}
+
+// SearchLogs 全文搜索日志（按时间倒序，游标分页）
+// 仅检索数据库存储后端中的日志（文件存储的执行数通过 SkippedFileExecutions 返回）；
+// FTS5 不可用或检索词过短时降级为 LIKE 逐行匹配
+func SearchLogs(q LogSearchQuery) (*models.LogSearchResult, error) {
+	terms := strings.Fields(q.Query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("搜索关键词不能为空")
+	}
+	cursor, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	useIndex := database.LogSearchEnabled()
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < logSearchMinTermLen {
+			useIndex = false
+		}
+	}
+
+	highlightExpr := "l.content"
+	if useIndex {
+		highlightExpr = fmt.Sprintf("snippet(%s, 0, char(2), char(3), '…', 64)", database.LogSearchTable)
+	}
+
+	query := database.GetDB().Table("logs AS l").
+		Select("l.*, t.name AS task_name, e.status AS execution_status, e.start_time AS execution_start_time, " +
+			"e.exit_code AS execution_exit_code, " + highlightExpr + " AS highlight").
+		Joins("LEFT JOIN executions e ON e.id = l.execution_id").
+		Joins("LEFT JOIN tasks t ON t.id = l.task_id")
+
+	if useIndex {
+		quoted := make([]string, 0, len(terms))
+		for _, term := range terms {
+			quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+		}
+		query = query.Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.rowid = l.rowid", database.LogSearchTable)).
+			Where(database.LogSearchTable+" MATCH ?", strings.Join(quoted, " AND "))
+	} else {
+		for _, term := range terms {
+			query = query.Where(`l.content LIKE ? ESCAPE '\'`, "%"+escapeLike(term)+"%")
+		}
+	}
+
+	if len(q.TaskIDs) > 0 {
+		query = query.Where("l.task_id IN ?", q.TaskIDs)
+	}
+	if len(q.Levels) > 0 {
+		query = query.Where("l.level IN ?", q.Levels)
+	}
+	if q.TimeRange.Start != nil {
+		query = query.Where("l.timestamp >= ?", q.TimeRange.Start.In(BeijingLocation))
+	}
+	if q.TimeRange.End != nil {
+		query = query.Where("l.timestamp < ?", q.TimeRange.End.In(BeijingLocation))
+	}
+	if cursor != nil {
+		query = query.Where("(l.timestamp < ? OR (l.timestamp = ? AND l.id < ?))", cursor.Time, cursor.Time, cursor.ID)
+	}
+
+	hits := make([]models.LogSearchHit, 0, logSearchPageSize+1)
+	if err := query.Order("l.timestamp DESC, l.id DESC").Limit(logSearchPageSize + 1).Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+
+	result := &models.LogSearchResult{Hits: hits}
+	if result.SkippedFileExecutions, err = countFileExecutions(q); err != nil {
+		return nil, err
+	}
+	if len(hits) > logSearchPageSize {
+		result.Hits = hits[:logSearchPageSize]
+		last := result.Hits[len(result.Hits)-1]
+		result.NextCursor = encodeCursor(last.Timestamp, last.ID)
+	}
+
+	for i := range result.Hits {
+		hit := &result.Hits[i]
+		if !useIndex {
+			hit.Highlight = markTerms(hit.Content, terms)
+		}
+		hit.Highlight = strings.NewReplacer(markOpen, "<mark>", markClose, "</mark>").
+			Replace(html.EscapeString(hit.Highlight))
+	}
+	return result, nil
+}
+
+// countFileExecutions 统计满足任务与时间筛选、日志使用文件存储的执行数
+func countFileExecutions(q LogSearchQuery) (int64, error) {
+	query := database.GetDB().Model(&models.LogFile{})
+	if len(q.TaskIDs) > 0 {
+		query = query.Where("task_id IN ?", q.TaskIDs)
+	}
+	if q.TimeRange.Start != nil {
+		query = query.Where("last_time >= ?", q.TimeRange.Start.In(BeijingLocation))
+	}
+	if q.TimeRange.End != nil {
+		query = query.Where("first_time < ?", q.TimeRange.End.In(BeijingLocation))
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// markTerms 截取首个命中附近的片段，并用标记包裹命中的检索词（ASCII 不区分大小写，与 LIKE 一致）
+func markTerms(content string, terms []string) string {
+	lower := strings.ToLower(content)
+	if len(lower) != len(content) {
+		// 大小写转换改变了字节长度，无法按偏移对应，仅做截取
+		lower = content
+	}
+
+	first := -1
+	for _, term := range terms {
+		if idx := strings.Index(lower, strings.ToLower(term)); idx >= 0 && (first < 0 || idx < first) {
+			first = idx
+		}
+	}
+
+	start, end := 0, len(content)
+	if len(content) > logSearchSnippetSize && first >= 0 {
+		start = max(first-logSearchSnippetSize/4, 0)
+		end = min(start+logSearchSnippetSize, len(content))
+		for start > 0 && !utf8.RuneStart(content[start]) {
+			start--
+		}
+		for end < len(content) && !utf8.RuneStart(content[end]) {
+			end++
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	segment, segmentLower := content[start:end], lower[start:end]
+	for pos := 0; pos < len(segment); {
+		matched := 0
+		for _, term := range terms {
+			t := strings.ToLower(term)
+			if strings.HasPrefix(segmentLower[pos:], t) && len(t) > matched {
+				matched = len(t)
+			}
+		}
+		if matched > 0 {
+			b.WriteString(markOpen + segment[pos:pos+matched] + markClose)
+			pos += matched
+			continue
+		}
+		b.WriteByte(segment[pos])
+		pos++
+	}
+	if end < len(content) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"scriptguard/backend/database"
+	"scriptguard/backend/models"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// 溢出文件后缀（文件名为 <execution_id> + spillFileSuffix）
+const spillFileSuffix = ".spill.jsonl"
+
+// spillOffsetSuffix 补录进度文件后缀（记录已补录到的字节偏移，中断后从该位置继续）
+const spillOffsetSuffix = ".offset"
+
+// logSpill 日志溢出暂存文件
+// 单次执行的日志写入队列满时，溢出的日志按 JSON 行追加到磁盘，执行结束后再统一补录，
+// 保证高输出场景下不丢日志，同时不阻塞 pipe 读取
+type logSpill struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	w     *bufio.Writer
+	count int
+}
+
+func newLogSpill(dir, executionID string) *logSpill {
+	return &logSpill{path: filepath.Join(dir, executionID+spillFileSuffix)}
+}
+
+// Write 追加一条溢出日志（并发安全，首次写入时才创建文件）
+func (s *logSpill) Write(entry *models.Log) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		s.file = f
+		s.w = bufio.NewWriterSize(f, 64*1024)
+	}
+
+	// 写入前分配 ID，补录中断后重试时可按 ID 去重
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := s.w.Write(data); err != nil {
+		return err
+	}
+	s.count++
+	return nil
+}
+
+// Flush 将缓冲的溢出日志写入文件（由写入协程定时调用，程序崩溃时最多丢失一个刷新间隔内的溢出日志）
+func (s *logSpill) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return nil
+	}
+	return s.w.Flush()
+}
+
+// Count 已暂存的日志条数
+func (s *logSpill) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Path 溢出文件路径
+func (s *logSpill) Path() string {
+	return s.path
+}
+
+// Ingest 关闭溢出文件并按批补录；全部补录成功后删除文件，失败则保留文件作为日志附件
+func (s *logSpill) Ingest(save func(batch []*models.Log) error) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return 0, nil
+	}
+	flushErr := s.w.Flush()
+	closeErr := s.file.Close()
+	s.file = nil
+	s.w = nil
+	if err := errors.Join(flushErr, closeErr); err != nil {
+		return 0, fmt.Errorf("关闭日志溢出文件失败: %w", err)
+	}
+
+	return ingestSpillFile(s.path, save)
+}
+
+// ingestSpillFile 读取溢出文件并按批补录，成功后删除文件
+// 每批补录成功后记录偏移，中断（补录失败或进程退出）后再次补录时跳过已补录的部分
+func ingestSpillFile(path string, save func(batch []*models.Log) error) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+
+	offsetPath := path + spillOffsetSuffix
+	offset := readSpillOffset(offsetPath)
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return 0, err
+		}
+	}
+
+	reader := bufio.NewReaderSize(f, 64*1024)
+	batch := make([]*models.Log, 0, logBatchSize)
+	total := 0
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			offset += int64(len(line))
+			entry := &models.Log{}
+			// 进程崩溃时最后一行可能不完整，跳过即可
+			if err := json.Unmarshal(line, entry); err == nil {
+				batch = append(batch, entry)
+			}
+			if len(batch) >= logBatchSize {
+				if err := save(batch); err != nil {
+					f.Close()
+					return total, err
+				}
+				total += len(batch)
+				batch = make([]*models.Log, 0, logBatchSize)
+				writeSpillOffset(offsetPath, offset)
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			f.Close()
+			return total, readErr
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := save(batch); err != nil {
+			f.Close()
+			return total, err
+		}
+		total += len(batch)
+	}
+
+	f.Close()
+	if err := os.Remove(path); err != nil {
+		log.Printf("删除日志溢出文件失败(path=%s): %v", path, err)
+	}
+	if err := os.Remove(offsetPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("删除日志补录进度文件失败(path=%s): %v", offsetPath, err)
+	}
+	return total, nil
+}
+
+// readSpillOffset 读取已补录到的字节偏移（不存在或无法解析时从头补录）
+func readSpillOffset(path string) int64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || offset < 0 {
+		return 0
+	}
+	return offset
+}
+
+// writeSpillOffset 记录已补录到的字节偏移（失败只记录日志，重试时按日志 ID 去重）
+func writeSpillOffset(path string, offset int64) {
+	if err := os.WriteFile(path, []byte(strconv.FormatInt(offset, 10)), 0644); err != nil {
+		log.Printf("记录日志补录进度失败(path=%s): %v", path, err)
+	}
+}
+
+// recoverySink 遗留溢出日志的补录目标：执行已使用文件存储时追加到其日志文件，否则写入数据库
+// （查询时合并两种存储，文件无法打开时写入数据库也不会丢失）
+func recoverySink(executionID string) logSink {
+	var meta models.LogFile
+	if err := database.GetDB().Where("execution_id = ?", executionID).Limit(1).Find(&meta).Error; err != nil || meta.ExecutionID == "" {
+		return dbLogSink{}
+	}
+	sink, err := reopenFileLogSink(&meta)
+	if err != nil {
+		log.Printf("打开执行日志文件失败，溢出日志改为写入数据库(execution_id=%s): %v", executionID, err)
+		return dbLogSink{}
+	}
+	return sink
+}
+
+// RecoverSpilledLogs 补录上次异常退出时遗留的日志溢出文件
+func (s *ExecutorService) RecoverSpilledLogs() {
+	dir := s.spillDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("读取日志溢出目录失败(dir=%s): %v", dir, err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), spillFileSuffix) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		executionID := strings.TrimSuffix(entry.Name(), spillFileSuffix)
+		sink := recoverySink(executionID)
+		n, err := ingestSpillFile(path, syncedWrite(sink))
+		if closeErr := sink.Close(); closeErr != nil {
+			log.Printf("关闭日志存储失败(execution_id=%s): %v", executionID, closeErr)
+		}
+		if err != nil {
+			log.Printf("补录遗留日志溢出文件失败(path=%s): %v", path, err)
+			continue
+		}
+		log.Printf("已补录遗留日志溢出文件 %s，共 %d 条", entry.Name(), n)
+	}
+}
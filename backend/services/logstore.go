@@ -0,0 +1,320 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"scriptguard/backend/database"
+	"scriptguard/backend/models"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 文件存储后端常量
+const (
+	logFileChunkLines    = 1000            // 单个压缩块最多行数
+	logFileChunkInterval = 5 * time.Second // 未满块的最长缓冲时间
+)
+
+// logSink 日志持久化后端
+type logSink interface {
+	Write(batch []*models.Log) error
+	Flush() error // 写入已超过缓冲时间的日志（由写入协程定时调用）
+	Sync() error  // 立即写入全部缓冲
+	Close() error
+}
+
+// dbLogSink 数据库后端：每行日志一条 logs 记录
+type dbLogSink struct{}
+
+func (dbLogSink) Write(batch []*models.Log) error { return saveLogBatch(batch) }
+func (dbLogSink) Flush() error                    { return nil }
+func (dbLogSink) Sync() error                     { return nil }
+func (dbLogSink) Close() error                    { return nil }
+
+// fileLogSink 文件后端：日志按块写成连续的 gzip member，分块索引与统计写入 log_files 表
+type fileLogSink struct {
+	meta    *models.LogFile
+	file    *os.File
+	pending []*models.Log
+	since   time.Time
+}
+
+func newFileLogSink(dir string, execution *models.Execution) *fileLogSink {
+	month := execution.StartTime.Format("200601")
+	return &fileLogSink{
+		meta: &models.LogFile{
+			ExecutionID: execution.ID,
+			TaskID:      execution.TaskID,
+			Path:        filepath.Join(dir, month, execution.ID+".log.gz"),
+		},
+	}
+}
+
+// reopenFileLogSink 打开执行已有的日志文件继续追加（补录遗留的溢出日志时使用）
+func reopenFileLogSink(meta *models.LogFile) (*fileLogSink, error) {
+	f, err := os.OpenFile(meta.Path, os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// 丢弃最后一个已登记块之后的数据（写入中断留下的不完整块）
+	sink := &fileLogSink{meta: meta, file: f}
+	if err := sink.rewind(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return sink, nil
+}
+
+// syncedWrite 写入后立即落盘，供按批记录补录进度的溢出日志补录使用
+func syncedWrite(sink logSink) func(batch []*models.Log) error {
+	return func(batch []*models.Log) error {
+		if err := sink.Write(batch); err != nil {
+			return err
+		}
+		return sink.Sync()
+	}
+}
+
+// Write 缓冲日志，满块或超过缓冲时间后落盘
+func (s *fileLogSink) Write(batch []*models.Log) error {
+	if len(s.pending) == 0 {
+		s.since = time.Now()
+	}
+	for _, entry := range batch {
+		if entry.ID == "" {
+			entry.ID = uuid.New().String()
+		}
+		s.pending = append(s.pending, entry)
+	}
+	if len(s.pending) >= logFileChunkLines || time.Since(s.since) >= logFileChunkInterval {
+		return s.flushChunk()
+	}
+	return nil
+}
+
+// Flush 缓冲超过最长缓冲时间时落盘（执行长时间无新输出时也能及时写入）
+func (s *fileLogSink) Flush() error {
+	if len(s.pending) == 0 || time.Since(s.since) < logFileChunkInterval {
+		return nil
+	}
+	return s.flushChunk()
+}
+
+// Sync 立即将缓冲写为一个块
+func (s *fileLogSink) Sync() error {
+	return s.flushChunk()
+}
+
+// Close 写入剩余缓冲并关闭文件
+func (s *fileLogSink) Close() error {
+	err := s.flushChunk()
+	if s.file != nil {
+		err = errors.Join(err, s.file.Close())
+		s.file = nil
+	}
+	return err
+}
+
+// flushChunk 将缓冲写为一个 gzip member，并更新元数据
+func (s *fileLogSink) flushChunk() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	if s.file == nil {
+		if err := os.MkdirAll(filepath.Dir(s.meta.Path), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(s.meta.Path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		s.file = f
+	}
+
+	chunk := models.LogChunk{
+		Offset: s.meta.StoredBytes,
+		Line:   s.meta.Lines,
+		Count:  len(s.pending),
+		Start:  s.pending[0].Timestamp,
+		End:    s.pending[0].Timestamp,
+	}
+
+	counter := &countingWriter{w: s.file}
+	rawBytes, err := writeLogChunk(counter, s.pending, &chunk)
+	if err != nil {
+		// 丢弃写了一半的块，下一块仍从已登记的位置开始，避免后续块的偏移指向残缺数据
+		if rewindErr := s.rewind(); rewindErr != nil {
+			return errors.Join(err, rewindErr)
+		}
+		return err
+	}
+	chunk.Size = counter.n
+
+	meta := s.meta
+	if meta.Lines == 0 || chunk.Start.Before(meta.FirstTime) {
+		meta.FirstTime = chunk.Start
+	}
+	if chunk.End.After(meta.LastTime) {
+		meta.LastTime = chunk.End
+	}
+	meta.Lines += chunk.Count
+	meta.RawBytes += rawBytes
+	meta.StoredBytes += chunk.Size
+	meta.Chunks = append(meta.Chunks, chunk)
+	s.pending = s.pending[:0]
+
+	// 每块落盘后同步元数据，运行中也能按索引读取已写入部分
+	return database.GetDB().Save(meta).Error
+}
+
+// writeLogChunk 将日志写为一个 gzip member，并记录块内的时间范围
+func writeLogChunk(w io.Writer, entries []*models.Log, chunk *models.LogChunk) (int64, error) {
+	gz := gzip.NewWriter(w)
+	var rawBytes int64
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			gz.Close()
+			return 0, err
+		}
+		data = append(data, '\n')
+		if _, err := gz.Write(data); err != nil {
+			gz.Close()
+			return 0, err
+		}
+		rawBytes += int64(len(data))
+		if entry.Timestamp.Before(chunk.Start) {
+			chunk.Start = entry.Timestamp
+		}
+		if entry.Timestamp.After(chunk.End) {
+			chunk.End = entry.Timestamp
+		}
+	}
+	return rawBytes, gz.Close()
+}
+
+// rewind 将日志文件截断到已登记的长度，并把写入位置移回末尾
+func (s *fileLogSink) rewind() error {
+	if err := s.file.Truncate(s.meta.StoredBytes); err != nil {
+		return err
+	}
+	_, err := s.file.Seek(s.meta.StoredBytes, io.SeekStart)
+	return err
+}
+
+// countingWriter 统计写入字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// readLogChunks 按索引读取指定块的日志
+func readLogChunks(path string, chunks []models.LogChunk) ([]models.Log, error) {
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	logs := make([]models.Log, 0)
+	for _, chunk := range chunks {
+		gz, err := gzip.NewReader(io.NewSectionReader(f, chunk.Offset, chunk.Size))
+		if err != nil {
+			return nil, fmt.Errorf("读取日志块失败(offset=%d): %w", chunk.Offset, err)
+		}
+		gz.Multistream(false)
+
+		reader := bufio.NewReaderSize(gz, 64*1024)
+		for {
+			line, readErr := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				var entry models.Log
+				if err := json.Unmarshal(line, &entry); err == nil {
+					logs = append(logs, entry)
+				}
+			}
+			if errors.Is(readErr, io.EOF) {
+				break
+			}
+			if readErr != nil {
+				gz.Close()
+				return nil, readErr
+			}
+		}
+		gz.Close()
+	}
+	return logs, nil
+}
+
+// ReadLogFile 读取文件后端中单次执行的全部日志（按时间正序）
+func ReadLogFile(meta *models.LogFile) ([]models.Log, error) {
+	logs, err := readLogChunks(meta.Path, meta.Chunks)
+	if err != nil {
+		return nil, err
+	}
+	sortLogs(logs)
+	return logs, nil
+}
+
+// QueryRecentLogs 查询最新 N 条日志（按时间正序），透明合并数据库与文件两种后端
+// executionID 优先；均为空时查询全部日志
+func QueryRecentLogs(executionID, taskID string, limit int) ([]models.Log, error) {
+	logs, _, err := fetchLogs(models.LogQuery{ExecutionID: executionID, TaskID: taskID}, nil, true, limit)
+	if err != nil {
+		return nil, err
+	}
+	sortLogs(logs)
+	return logs, nil
+}
+
+// sortLogs 按时间正序排序（时间相同按 ID，保证稳定）
+func sortLogs(logs []models.Log) {
+	sort.SliceStable(logs, func(i, j int) bool {
+		if logs[i].Timestamp.Equal(logs[j].Timestamp) {
+			return logs[i].ID < logs[j].ID
+		}
+		return logs[i].Timestamp.Before(logs[j].Timestamp)
+	})
+}
+
+// RemoveLogFilesBefore 删除最晚日志早于 cutoff 的日志文件及其元数据
+func RemoveLogFilesBefore(cutoff time.Time) (int, error) {
+	db := database.GetDB()
+
+	var metas []models.LogFile
+	if err := db.Where("last_time < ?", cutoff).Find(&metas).Error; err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, meta := range metas {
+		if err := os.Remove(meta.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("删除日志文件失败(path=%s): %v", meta.Path, err)
+			continue
+		}
+		if err := db.Delete(&models.LogFile{}, "execution_id = ?", meta.ExecutionID).Error; err != nil {
+			log.Printf("删除日志文件元数据失败(execution_id=%s): %v", meta.ExecutionID, err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
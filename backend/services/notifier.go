@@ -1,157 +1,314 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"scriptguard/backend/database"
 	"scriptguard/backend/models"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// loadedChannel 已加载的通知渠道
+type loadedChannel struct {
+	id      string
+	typ     string
+	name    string
+	channel Channel
+	sender  *channelSender
+}
+
 type NotifierService struct {
-	mu              sync.RWMutex
-	dingTalkWebhook string
-	wecomWebhook    string
-	client          *http.Client
+	mu       sync.RWMutex
+	channels []loadedChannel
+	client   *http.Client
+	redactor *Redactor // 发送前脱敏
+	deduper  *alertDeduper
+
+	// 告警发件箱投递
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
-func NewNotifierService(dingTalkWebhook, wecomWebhook string) *NotifierService {
+func NewNotifierService() *NotifierService {
 	return &NotifierService{
-		dingTalkWebhook: dingTalkWebhook,
-		wecomWebhook:    wecomWebhook,
 		client: &http.Client{
 			Timeout: 8 * time.Second,
 		},
+		deduper: newAlertDeduper(defaultNotifyDedupWindow),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+// SetDedupWindow 设置相同告警的去重窗口（0 表示不去重）
+func (s *NotifierService) SetDedupWindow(window time.Duration) {
+	s.deduper.SetWindow(window)
+}
+
+// LoadChannels 从数据库加载已启用的通知渠道（用于启动及渠道变更后热更新）
+// 配置不完整的渠道记录日志后跳过，不影响其他渠道
+func (s *NotifierService) LoadChannels() error {
+	var rows []models.NotifyChannel
+	if err := database.GetDB().Where("enabled = ?", true).Order("created_at").Find(&rows).Error; err != nil {
+		return fmt.Errorf("加载通知渠道失败: %w", err)
+	}
+	// 沿用已加载渠道的发送窗口，避免重新加载后频率限制从零计算
+	windows := make(map[string]*rateWindow)
+	for _, c := range s.getChannels() {
+		windows[c.id] = c.sender.window
+	}
+
+	channels := make([]loadedChannel, 0, len(rows))
+	for _, row := range rows {
+		channel, err := newChannel(row.Type, row.Config, s.client)
+		if err != nil {
+			log.Printf("通知渠道配置无效，已跳过(name=%s, type=%s): %v", row.Name, row.Type, err)
+			continue
+		}
+		channels = append(channels, loadedChannel{
+			id:      row.ID,
+			typ:     row.Type,
+			name:    row.Name,
+			channel: channel,
+			sender: newChannelSender(row.Name, channel, windows[row.ID], func(n *models.Notification) {
+				s.deliver(channel, n)
+			}),
+		})
+	}
+
+	s.mu.Lock()
+	old := s.channels
+	s.channels = channels
+	s.mu.Unlock()
+
+	// 旧渠道的发送器在发完已排队的告警后退出
+	for _, c := range old {
+		c.sender.Close()
 	}
+	// 渠道恢复启用或修正配置后补发积压的告警（未加载渠道的告警在发件箱中保持待发送）
+	s.wakeOutbox()
+	return nil
 }
 
-// SetWebhooks 用于运行时热更新告警配置
-func (s *NotifierService) SetWebhooks(dingTalkWebhook, wecomWebhook string) {
+// SetRedactor 设置敏感信息脱敏器
+func (s *NotifierService) SetRedactor(redactor *Redactor) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.dingTalkWebhook = dingTalkWebhook
-	s.wecomWebhook = wecomWebhook
+	s.redactor = redactor
 }
 
-func (s *NotifierService) getWebhooks() (string, string) {
+func (s *NotifierService) getRedactor() *Redactor {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.dingTalkWebhook, s.wecomWebhook
+	return s.redactor
 }
 
-// NotifyFailure 发送失败通知
-func (s *NotifierService) NotifyFailure(task *models.Task, execution *models.Execution, err error) {
-	message := fmt.Sprintf(
-		"⚠️ 脚本执行失败\n\n任务名称: %s\n脚本路径: %s\n环境: %s\n错误信息: %s\n执行ID: %s",
-		task.Name,
-		task.ScriptPath,
-		task.CondaEnv,
-		err.Error(),
-		execution.ID,
-	)
-
-	ding, wecom := s.getWebhooks()
+func (s *NotifierService) getChannels() []loadedChannel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.channels
+}
 
-	if ding != "" {
-		if sendErr := s.sendDingTalk(message); sendErr != nil {
-			log.Printf("发送钉钉告警失败: %v", sendErr)
+// NotifyStall 发送执行卡住通知（长时间无输出，尚未达到执行超时）
+// killErr 为按配置终止进程失败的原因
+func (s *NotifierService) NotifyStall(task *models.Task, execution *models.Execution, idle time.Duration, killErr error) {
+	action := "仅告警，进程继续运行"
+	if task.StallAction == models.StallActionKill {
+		action = "已终止进程"
+		if killErr != nil {
+			action = fmt.Sprintf("终止进程失败，进程可能仍在运行: %v", killErr)
+		}
+	}
+	data := NotifyTemplateData{
+		Execution: execution,
+		Duration:  fmt.Sprintf("%d 分钟", int(idle.Minutes())),
+	}
+	if killErr != nil {
+		data.Error = killErr.Error()
+	}
+	s.notifyEvent(task, models.NotifyEventStall, data, func() *Notification {
+		return &Notification{
+			Title: "⏳ 脚本疑似卡住",
+			Level: NotificationLevelWarning,
+			Fields: []NotificationField{
+				{"任务名称", task.Name},
+				{"脚本路径", task.ScriptPath},
+				{"环境", task.CondaEnv},
+				{"主机", hostName()},
+				{"无输出时长", fmt.Sprintf("%d 分钟", int(idle.Minutes()))},
+				{"处理方式", action},
+				{"开始时间", execution.StartTime.Format("2006-01-02 15:04:05")},
+				{"执行ID", execution.ID},
+			},
+			ExecutionID: execution.ID,
+			Task:        task,
+			Execution:   execution,
 		}
+	})
+}
+
+// broadcast 脱敏后按渠道写入告警发件箱，由后台投递（channelIDs 为空时发送到全部已启用渠道）
+// 渠道以数据库记录为准：指定的渠道已停用时告警保持待发送，配置无效时记为失败，均不会丢弃
+func (s *NotifierService) broadcast(n *Notification, channelIDs []string) {
+	// 消息可能包含脚本输出中的密钥，入库前统一脱敏
+	n = n.redacted(s.getRedactor())
+
+	query := database.GetDB().Order("created_at")
+	if len(channelIDs) > 0 {
+		query = query.Where("id IN ?", channelIDs)
+	} else {
+		query = query.Where("enabled = ?", true)
+	}
+	var rows []models.NotifyChannel
+	if err := query.Find(&rows).Error; err != nil {
+		log.Printf("读取通知渠道失败，告警未写入发件箱(title=%s): %v", n.Title, err)
+		return
 	}
 
-	if wecom != "" {
-		if sendErr := s.sendWeCom(message); sendErr != nil {
-			log.Printf("发送企业微信告警失败: %v", sendErr)
+	loaded := make(map[string]bool)
+	for _, c := range s.getChannels() {
+		loaded[c.id] = true
+	}
+	for _, row := range rows {
+		var loadErr error
+		if row.Enabled && !loaded[row.ID] {
+			_, loadErr = newChannel(row.Type, row.Config, s.client)
+		}
+		if err := s.persist(n, row, loadErr); err != nil {
+			log.Printf("写入告警发件箱失败(channel=%s): %v", row.Name, err)
 		}
 	}
+	s.wakeOutbox()
 }
 
-// sendDingTalk 发送钉钉通知
-func (s *NotifierService) sendDingTalk(message string) error {
-	payload := map[string]interface{}{
-		"msgtype": "text",
-		"text": map[string]string{
-			"content": message,
-		},
+// statusLabel 执行状态的中文名称
+func statusLabel(status models.ExecutionStatus) string {
+	switch status {
+	case models.StatusSuccess:
+		return "成功"
+	case models.StatusFailed:
+		return "失败"
+	case models.StatusWarning:
+		return "警告"
+	case models.StatusRunning:
+		return "运行中"
 	}
-
-	ding, _ := s.getWebhooks()
-	return s.sendWebhook(ding, payload)
+	return string(status)
 }
 
-// sendWeCom 发送企业微信通知
-func (s *NotifierService) sendWeCom(message string) error {
-	payload := map[string]interface{}{
-		"msgtype": "text",
-		"text": map[string]string{
-			"content": message,
-		},
+// testNotification 测试通知内容
+func testNotification() *Notification {
+	return &Notification{
+		Title:  "✅ ScriptGuard 测试通知",
+		Level:  NotificationLevelInfo,
+		Fields: []NotificationField{{"时间", time.Now().Format("2006-01-02 15:04:05")}},
+		Note:   "如果您收到此消息，说明告警配置正确！",
 	}
+}
 
-	_, wecom := s.getWebhooks()
-	return s.sendWebhook(wecom, payload)
+// sendTestNotification 直接发送测试通知（不经过发件箱，便于立即返回结果）
+func sendTestNotification(channel Channel) error {
+	_, err := channel.Send(testNotification())
+	return err
 }
 
-// sendWebhook 发送webhook请求（带超时和状态码检查）
-func (s *NotifierService) sendWebhook(url string, payload interface{}) error {
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return err
+// SG-013: SendTest 发送测试通知
+// 未传入 webhook 时使用已保存的同类型渠道（优先系统设置中的渠道）
+func (s *NotifierService) SendTest(target string, webhook string) error {
+	url := strings.TrimSpace(webhook)
+	if url == "" {
+		channels := s.getChannels()
+		id := legacyChannelID(target)
+		for _, c := range channels {
+			if id != "" && c.id == id {
+				return sendTestNotification(c.channel)
+			}
+		}
+		for _, c := range channels {
+			if c.typ == target {
+				return sendTestNotification(c.channel)
+			}
+		}
+		if !slices.Contains(ChannelTypes(), target) {
+			return fmt.Errorf("未知通知类型: %s", target)
+		}
+		return fmt.Errorf("Webhook 未配置")
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(data))
+	channel, err := newChannel(target, map[string]string{"webhook": url}, s.client)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return sendTestNotification(channel)
+}
 
-	resp, err := s.client.Do(req)
+// TestChannel 按渠道配置发送测试通知（渠道可未保存）
+func (s *NotifierService) TestChannel(row models.NotifyChannel) error {
+	channel, err := newChannel(row.Type, row.Config, s.client)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	return sendTestNotification(channel)
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return fmt.Errorf("webhook返回非2xx: %s, body=%s", resp.Status, strings.TrimSpace(string(body)))
+// legacyChannelID 旧版 webhook 配置对应的渠道 ID
+func legacyChannelID(typ string) string {
+	switch typ {
+	case models.ChannelTypeDingTalk:
+		return models.LegacyDingTalkChannelID
+	case models.ChannelTypeWeCom:
+		return models.LegacyWeComChannelID
 	}
-
-	return nil
+	return ""
 }
 
-// SG-013: SendTest 发送测试通知
-func (s *NotifierService) SendTest(target string, webhook string) error {
-	message := fmt.Sprintf("✅ ScriptGuard 测试通知\n时间: %s\n\n如果您收到此消息，说明告警配置正确！",
-		time.Now().Format("2006-01-02 15:04:05"))
-
-	payload := map[string]interface{}{
-		"msgtype": "text",
-		"text": map[string]string{
-			"content": message,
-		},
+// SyncLegacyChannels 将系统设置中的钉钉、企业微信 webhook 同步为渠道记录
+// webhook 为空时删除对应渠道（仍被引用时拒绝）；已有渠道保留启用状态、名称与其他配置
+func SyncLegacyChannels(dingTalkWebhook, wecomWebhook string) error {
+	legacy := []struct {
+		typ, name, webhook string
+	}{
+		{models.ChannelTypeDingTalk, "钉钉", dingTalkWebhook},
+		{models.ChannelTypeWeCom, "企业微信", wecomWebhook},
 	}
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, item := range legacy {
+			id := legacyChannelID(item.typ)
+			webhook := strings.TrimSpace(item.webhook)
+			if webhook == "" {
+				if err := CheckChannelUnused(tx, id); err != nil {
+					return fmt.Errorf("无法删除%s渠道: %w", item.name, err)
+				}
+				if err := tx.Delete(&models.NotifyChannel{}, "id = ?", id).Error; err != nil {
+					return err
+				}
+				continue
+			}
 
-	url := strings.TrimSpace(webhook)
-	if url == "" {
-		// 如果未传入 webhook，使用已保存的配置
-		ding, wecom := s.getWebhooks()
-		switch target {
-		case "dingtalk":
-			url = ding
-		case "wecom":
-			url = wecom
-		default:
-			return fmt.Errorf("未知通知类型: %s", target)
+			var row models.NotifyChannel
+			err := tx.Where("id = ?", id).Limit(1).Find(&row).Error
+			if err != nil {
+				return err
+			}
+			if row.ID == "" {
+				row = models.NotifyChannel{ID: id, Type: item.typ, Name: item.name, Enabled: true}
+			}
+			// 仅更新 webhook，保留渠道上的其他配置（如签名密钥）
+			if row.Config == nil {
+				row.Config = models.StringMap{}
+			}
+			row.Config["webhook"] = webhook
+			if err := tx.Save(&row).Error; err != nil {
+				return err
+			}
 		}
-	}
-
-	if url == "" {
-		return fmt.Errorf("Webhook 未配置")
-	}
-
-	return s.sendWebhook(url, payload)
+		return nil
+	})
 }
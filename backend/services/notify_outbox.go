@@ -0,0 +1,229 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"scriptguard/backend/database"
+	"scriptguard/backend/models"
+	"time"
+)
+
+// 告警发件箱参数
+const (
+	outboxPollInterval      = 5 * time.Second
+	outboxBatchSize         = 50
+	maxNotifyAttempts       = 8                // 含首次发送，超过后标记为失败
+	notifyRetryBase         = 30 * time.Second // 第 n 次失败后等待 base·2^(n-1)
+	notifyRetryMax          = time.Hour
+	maxNotifyResponseLength = 1000 // 记录的响应摘要最多字符数
+	maxNotificationLimit    = 1000
+)
+
+// notifyBackoff 第 attempts 次发送失败后的重试间隔（指数退避）
+func notifyBackoff(attempts int) time.Duration {
+	d := notifyRetryBase
+	for i := 1; i < attempts && d < notifyRetryMax; i++ {
+		d *= 2
+	}
+	return min(d, notifyRetryMax)
+}
+
+// persist 将通知按渠道写入发件箱，由后台投递（loadErr 非空时直接记为失败，可在发送记录中查看并重发）
+func (s *NotifierService) persist(n *Notification, channel models.NotifyChannel, loadErr error) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	now := NowBeijing()
+	row := models.Notification{
+		ChannelID:     channel.ID,
+		ChannelName:   channel.Name,
+		Event:         n.Event,
+		Title:         n.Title,
+		Payload:       string(payload),
+		Status:        models.NotificationPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if n.Task != nil {
+		row.TaskID = n.Task.ID
+	}
+	if loadErr != nil {
+		row.Status = models.NotificationFailed
+		row.LastError = fmt.Sprintf("渠道配置无效: %v", loadErr)
+	}
+	return database.GetDB().Create(&row).Error
+}
+
+// Start 启动发件箱投递：上次退出时未发完的告警重新排队
+func (s *NotifierService) Start() {
+	err := database.GetDB().Model(&models.Notification{}).
+		Where("status = ?", models.NotificationSending).
+		Update("status", models.NotificationPending).Error
+	if err != nil {
+		log.Printf("恢复未发送的告警失败: %v", err)
+	}
+	go s.runOutbox()
+}
+
+// Stop 停止发件箱投递（已交给渠道的告警下次启动时重发）
+func (s *NotifierService) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// wakeOutbox 通知后台立即投递
+func (s *NotifierService) wakeOutbox() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *NotifierService) runOutbox() {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	for {
+		s.dispatchOutbox()
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+		case <-ticker.C:
+			s.flushSuppressed()
+		}
+	}
+}
+
+// dispatchOutbox 将到期的待发送告警交给对应渠道的发送器
+// 渠道已删除的告警标记为失败；渠道停用或配置无效（未加载）时告警保持待发送，渠道恢复后补发
+func (s *NotifierService) dispatchOutbox() {
+	db := database.GetDB()
+	err := db.Model(&models.Notification{}).
+		Where("status = ? AND channel_id NOT IN (?)", models.NotificationPending, db.Model(&models.NotifyChannel{}).Select("id")).
+		Updates(map[string]any{
+			"status":     models.NotificationFailed,
+			"last_error": "渠道不存在",
+		}).Error
+	if err != nil {
+		log.Printf("更新已删除渠道的告警失败: %v", err)
+	}
+
+	channels := make(map[string]loadedChannel)
+	var ids []string
+	for _, c := range s.getChannels() {
+		channels[c.id] = c
+		ids = append(ids, c.id)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	var rows []models.Notification
+	err = db.Where("status = ? AND next_attempt_at <= ? AND channel_id IN ?", models.NotificationPending, NowBeijing(), ids).
+		Order("created_at").
+		Limit(outboxBatchSize).
+		Find(&rows).Error
+	if err != nil {
+		log.Printf("读取待发送告警失败: %v", err)
+		return
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		c := channels[row.ChannelID]
+
+		result := db.Model(row).
+			Where("status = ?", models.NotificationPending).
+			Update("status", models.NotificationSending)
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+		if !c.sender.Enqueue(row) {
+			// 渠道队列已满，留待下次投递
+			db.Model(row).Update("status", models.NotificationPending)
+		}
+	}
+}
+
+// deliver 发送一条发件箱中的告警并记录结果，失败时按指数退避安排重试
+func (s *NotifierService) deliver(channel Channel, row *models.Notification) {
+	var n Notification
+	if err := json.Unmarshal([]byte(row.Payload), &n); err != nil {
+		database.GetDB().Model(row).Updates(map[string]any{
+			"status":     models.NotificationFailed,
+			"last_error": fmt.Sprintf("解析通知内容失败: %v", err),
+		})
+		return
+	}
+	// 内容入库前已脱敏，渠道自行读取的内容（如完整日志）按当前规则脱敏
+	n.redactor = s.getRedactor()
+	// 任务不写入发件箱，供自定义模板使用的任务按 ID 重新读取（任务已删除时为空）
+	if row.TaskID != "" {
+		var task models.Task
+		if err := database.GetDB().Where("id = ?", row.TaskID).Limit(1).Find(&task).Error; err == nil && task.ID != "" {
+			n.Task = &task
+		}
+	}
+
+	response, err := channel.Send(&n)
+	attempts := row.Attempts + 1
+	now := NowBeijing()
+	updates := map[string]any{
+		"attempts": attempts,
+		"response": truncateRunes(response, maxNotifyResponseLength),
+	}
+	switch {
+	case err == nil:
+		updates["status"] = models.NotificationSent
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+	case attempts >= maxNotifyAttempts:
+		updates["status"] = models.NotificationFailed
+		updates["last_error"] = err.Error()
+		log.Printf("发送告警到渠道 %s 失败，已放弃(第 %d 次): %v", row.ChannelName, attempts, err)
+	default:
+		updates["status"] = models.NotificationPending
+		updates["next_attempt_at"] = now.Add(notifyBackoff(attempts))
+		updates["last_error"] = err.Error()
+		log.Printf("发送告警到渠道 %s 失败，稍后重试(第 %d 次): %v", row.ChannelName, attempts, err)
+	}
+	if err := database.GetDB().Model(row).Updates(updates).Error; err != nil {
+		log.Printf("更新告警发送状态失败(id=%s): %v", row.ID, err)
+	}
+}
+
+// ListNotifications 查询告警发送记录（按创建时间倒序，status 为空时不过滤）
+func (s *NotifierService) ListNotifications(status string, limit int) ([]models.Notification, error) {
+	if limit <= 0 || limit > maxNotificationLimit {
+		limit = maxNotificationLimit
+	}
+	query := database.GetDB().Order("created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var rows []models.Notification
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("查询告警发送记录失败: %w", err)
+	}
+	return rows, nil
+}
+
+// RetryNotification 重新发送一条已失败的告警（重新计算重试次数）
+func (s *NotifierService) RetryNotification(id string) error {
+	result := database.GetDB().Model(&models.Notification{}).
+		Where("id = ? AND status = ?", id, models.NotificationFailed).
+		Updates(map[string]any{
+			"status":          models.NotificationPending,
+			"attempts":        0,
+			"next_attempt_at": NowBeijing(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("重发告警失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("告警不存在或未处于失败状态")
+	}
+	s.wakeOutbox()
+	return nil
+}
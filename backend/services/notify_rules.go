@@ -0,0 +1,416 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"scriptguard/backend/database"
+	"scriptguard/backend/models"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxLongRunningMinutes long_running 规则的最大阈值（7 天）
+const maxLongRunningMinutes = 7 * 24 * 60
+
+// NotifyTemplateData 通知规则消息模板的数据
+type NotifyTemplateData struct {
+	Event     string
+	Task      *models.Task
+	Execution *models.Execution // skipped、long_running 等执行期间的事件为空
+	Previous  *models.Execution // 上一次已结束的执行（可能为空）
+	Error     string
+	Duration  string // 执行耗时或已运行时长
+	Host      string // 本机主机名
+	Stderr    string // 失败时 stderr 的最后几行
+	Traceback string // 失败时检测到的 Python traceback
+}
+
+// ValidateNotifyRules 校验任务的通知规则
+func ValidateNotifyRules(rules models.NotifyRules) error {
+	var channelIDs []string
+	for _, rule := range rules {
+		if !slices.Contains(models.NotifyEvents, rule.Event) {
+			return fmt.Errorf("未知的通知事件: %s", rule.Event)
+		}
+		// 按事件实际收到的数据形态（Execution、Previous 可能为空）试渲染，提前发现无法渲染的模板
+		if rule.Template != "" {
+			for _, sample := range notifyTemplateSamples(rule.Event) {
+				if _, err := renderNotifyTemplate(rule.Template, sample); err != nil {
+					return fmt.Errorf("%s 事件的消息模板非法: %w", rule.Event, err)
+				}
+			}
+		}
+		if rule.Event == models.NotifyEventLongRunning && (rule.ThresholdMinutes < 1 || rule.ThresholdMinutes > maxLongRunningMinutes) {
+			return fmt.Errorf("运行时长阈值超出允许范围：1~%d 分钟", maxLongRunningMinutes)
+		}
+		for _, id := range rule.Channels {
+			if !slices.Contains(channelIDs, id) {
+				channelIDs = append(channelIDs, id)
+			}
+		}
+	}
+	if len(channelIDs) == 0 {
+		return nil
+	}
+	var count int64
+	if err := database.GetDB().Model(&models.NotifyChannel{}).Where("id IN ?", channelIDs).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(channelIDs) {
+		return fmt.Errorf("通知规则引用了不存在的渠道")
+	}
+	return nil
+}
+
+// notifyTemplateSamples 事件渲染模板时可能收到的数据形态
+// skipped、long_running 在执行期间触发，没有 Execution；stall 没有 Previous；
+// recovery 一定有上一次（失败的）执行，其余执行结束事件的 Previous 在首次执行时为空
+func notifyTemplateSamples(event string) []NotifyTemplateData {
+	task := &models.Task{}
+	switch event {
+	case models.NotifyEventSkipped, models.NotifyEventLongRunning:
+		return []NotifyTemplateData{{Event: event, Task: task}}
+	case models.NotifyEventStall:
+		return []NotifyTemplateData{{Event: event, Task: task, Execution: &models.Execution{}}}
+	case models.NotifyEventRecovery:
+		return []NotifyTemplateData{{Event: event, Task: task, Execution: &models.Execution{}, Previous: &models.Execution{}}}
+	}
+	return []NotifyTemplateData{
+		{Event: event, Task: task, Execution: &models.Execution{}},
+		{Event: event, Task: task, Execution: &models.Execution{}, Previous: &models.Execution{}},
+	}
+}
+
+// CheckChannelUnused 渠道仍被任务通知规则或执行汇总引用时返回错误（删除渠道前检查）
+func CheckChannelUnused(tx *gorm.DB, channelID string) error {
+	var refs []string
+
+	var tasks []models.Task
+	// 先按 JSON 文本粗筛，再精确比对
+	err := tx.Select("id", "name", "notify_rules").
+		Where("notify_rules LIKE ?", "%"+channelID+"%").
+		Find(&tasks).Error
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if slices.ContainsFunc(task.NotifyRules, func(rule models.NotifyRule) bool {
+			return slices.Contains(rule.Channels, channelID)
+		}) {
+			refs = append(refs, "任务 "+task.Name)
+		}
+	}
+
+	var config models.Config
+	if err := tx.Where("key = ?", models.ConfigKeyDigestChannels).Limit(1).Find(&config).Error; err != nil {
+		return err
+	}
+	if slices.Contains(ParseDigestChannels(config.Value), channelID) {
+		refs = append(refs, "执行汇总")
+	}
+
+	if len(refs) > 0 {
+		return fmt.Errorf("渠道仍被以下配置引用: %s", strings.Join(refs, "、"))
+	}
+	return nil
+}
+
+// parseNotifyTemplate 编译消息模板（与通用 webhook 共用模板函数）
+func parseNotifyTemplate(text string) (*template.Template, error) {
+	return template.New("notify").Funcs(webhookFuncs).Option("missingkey=error").Parse(text)
+}
+
+// rulesFor 任务在某事件上生效的通知规则
+// 未配置规则时沿用 NotifyOnFailure：失败与卡住时通知全部渠道
+func rulesFor(task *models.Task, event string) []models.NotifyRule {
+	if len(task.NotifyRules) == 0 {
+		if task.NotifyOnFailure && (event == models.NotifyEventFailure || event == models.NotifyEventStall) {
+			return []models.NotifyRule{{Event: event}}
+		}
+		return nil
+	}
+	var rules []models.NotifyRule
+	for _, rule := range task.NotifyRules {
+		if rule.Event == event {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// executionEvents 执行结束后触发的事件（previous 为上一次已结束的执行，可为空）
+func executionEvents(execution, previous *models.Execution) []string {
+	prevFailed := previous != nil && previous.Status == models.StatusFailed
+	switch execution.Status {
+	case models.StatusFailed:
+		events := []string{models.NotifyEventFailure}
+		if !prevFailed {
+			events = append(events, models.NotifyEventFirstFailure)
+		}
+		if execution.TimedOut {
+			events = append(events, models.NotifyEventTimeout)
+		}
+		return events
+	case models.StatusSuccess, models.StatusWarning:
+		events := []string{models.NotifyEventSuccess}
+		if execution.Status == models.StatusWarning {
+			events = []string{models.NotifyEventWarning}
+		}
+		if prevFailed {
+			events = append(events, models.NotifyEventRecovery)
+		}
+		return events
+	}
+	return nil
+}
+
+// previousExecution 查询任务在该次执行之前最近一次已结束的执行
+func previousExecution(execution *models.Execution) *models.Execution {
+	var previous models.Execution
+	err := database.GetDB().
+		Where("task_id = ? AND id <> ? AND end_time IS NOT NULL AND start_time <= ?", execution.TaskID, execution.ID, execution.StartTime).
+		Order("start_time DESC").
+		Limit(1).
+		Find(&previous).Error
+	if err != nil || previous.ID == "" {
+		return nil
+	}
+	return &previous
+}
+
+// notifyEvent 按任务的通知规则发送事件通知
+// build 生成默认内容，只在存在生效规则时调用；规则配置了模板时以模板渲染结果作为正文
+func (s *NotifierService) notifyEvent(task *models.Task, event string, data NotifyTemplateData, build func() *Notification) {
+	if !dedupable(event) {
+		// 恢复前被去重的失败告警先汇总发送，避免重复次数丢失
+		for _, alert := range s.deduper.ResetTask(task.ID) {
+			s.sendSuppressed(task, alert)
+		}
+	}
+	rules := rulesFor(task, event)
+	if len(rules) == 0 {
+		return
+	}
+
+	// 相同任务、事件与错误的告警在去重窗口内只发送一次
+	summary := ""
+	if dedupable(event) {
+		send, firstAt, count := s.deduper.Check(task.ID, event, data.Error, NowBeijing())
+		if !send {
+			log.Printf("重复告警已去重(task_id=%s, event=%s, 第 %d 次)", task.ID, event, count)
+			return
+		}
+		if count > 1 {
+			summary = repeatSummary(event, firstAt, count)
+		}
+	}
+
+	data.Event = event
+	data.Task = task
+	data.Host = hostName()
+	for _, rule := range rules {
+		n := build()
+		if summary != "" {
+			n.Title += " · " + summary
+		}
+		s.sendRule(rule, n, data)
+	}
+}
+
+// flushSuppressed 汇总发送去重窗口已过、期间有重复未发送的告警
+func (s *NotifierService) flushSuppressed() {
+	for _, alert := range s.deduper.Expired(NowBeijing()) {
+		var task models.Task
+		if err := database.GetDB().First(&task, "id = ?", alert.TaskID).Error; err != nil {
+			log.Printf("汇总重复告警时读取任务失败(task_id=%s): %v", alert.TaskID, err)
+			continue
+		}
+		s.sendSuppressed(&task, alert)
+	}
+}
+
+// sendSuppressed 按事件的通知规则发送一条重复告警汇总
+func (s *NotifierService) sendSuppressed(task *models.Task, alert suppressedAlert) {
+	data := NotifyTemplateData{Event: alert.Event, Task: task, Host: hostName(), Error: alert.Message}
+	for _, rule := range rulesFor(task, alert.Event) {
+		n := &Notification{
+			Title: "🔁 重复告警汇总 · " + repeatSummary(alert.Event, alert.FirstAt, alert.Count),
+			Level: NotificationLevelWarning,
+			Fields: []NotificationField{
+				{"任务名称", task.Name},
+				{"主机", data.Host},
+				{"最近错误", alert.Message},
+			},
+			Note: fmt.Sprintf("去重窗口内另有 %d 次相同告警未单独发送", alert.Suppressed),
+			Task: task,
+		}
+		s.sendRule(rule, n, data)
+	}
+}
+
+// sendRule 按一条规则发送通知：配置了模板时以渲染结果作为正文，渲染失败时使用默认内容
+func (s *NotifierService) sendRule(rule models.NotifyRule, n *Notification, data NotifyTemplateData) {
+	if rule.Template != "" {
+		text, err := renderNotifyTemplate(rule.Template, data)
+		if err != nil {
+			log.Printf("渲染通知模板失败，使用默认内容(task_id=%s, event=%s): %v", data.Task.ID, data.Event, err)
+		} else {
+			n.Fields = nil
+			n.Note = text
+		}
+	}
+	n.Event = data.Event
+	s.broadcast(n, rule.Channels)
+}
+
+// renderNotifyTemplate 渲染通知规则的消息模板
+func renderNotifyTemplate(text string, data NotifyTemplateData) (string, error) {
+	tmpl, err := parseNotifyTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// NotifyExecution 执行结束后按任务的通知规则发送失败、成功、恢复、超时等通知
+func (s *NotifierService) NotifyExecution(task *models.Task, execution *models.Execution, err error) {
+	previous := previousExecution(execution)
+	data := NotifyTemplateData{
+		Execution: execution,
+		Previous:  previous,
+		Duration:  formatDurationMs(execution.DurationMs),
+		Host:      hostName(),
+	}
+	if err != nil {
+		data.Error = err.Error()
+	} else {
+		data.Error = execution.ErrorMessage
+	}
+	if execution.Status == models.StatusFailed {
+		data.Stderr, data.Traceback = failureContext(execution.ID)
+	}
+
+	for _, event := range executionEvents(execution, previous) {
+		event := event
+		s.notifyEvent(task, event, data, func() *Notification {
+			return executionNotification(task, execution, event, data)
+		})
+	}
+}
+
+// executionNotification 执行结束事件的默认通知内容（失败时附带退出码、stderr 摘录与 traceback）
+func executionNotification(task *models.Task, execution *models.Execution, event string, data NotifyTemplateData) *Notification {
+	n := &Notification{
+		Level: NotificationLevelInfo,
+		Fields: []NotificationField{
+			{"任务名称", task.Name},
+			{"脚本路径", task.ScriptPath},
+			{"环境", task.CondaEnv},
+			{"主机", data.Host},
+			{"状态", statusLabel(execution.Status)},
+		},
+		ExecutionID: execution.ID,
+		Task:        task,
+		Execution:   execution,
+	}
+	switch event {
+	case models.NotifyEventFailure:
+		n.Title = "⚠️ 脚本执行失败"
+	case models.NotifyEventFirstFailure:
+		n.Title = "⚠️ 脚本开始失败"
+	case models.NotifyEventTimeout:
+		n.Title = "⏰ 脚本执行超时"
+	case models.NotifyEventSuccess:
+		n.Title = "✅ 脚本执行成功"
+	case models.NotifyEventRecovery:
+		n.Title = "✅ 脚本已恢复正常"
+	case models.NotifyEventWarning:
+		n.Title = "⚠️ 脚本执行有警告"
+		n.Level = NotificationLevelWarning
+	}
+	errMessage := data.Error
+	if execution.Status == models.StatusFailed {
+		n.Level = NotificationLevelError
+		n.Fields = append(n.Fields, NotificationField{"退出码", fmt.Sprintf("%d", execution.ExitCode)})
+		n.Excerpt = data.Stderr
+		n.Traceback = data.Traceback
+		// "exit status N" 与退出码重复
+		if errMessage == fmt.Sprintf("exit status %d", execution.ExitCode) {
+			errMessage = ""
+		}
+	}
+	n.Fields = append(n.Fields, NotificationField{"耗时", data.Duration})
+	if errMessage != "" {
+		label := "错误信息"
+		if execution.Status != models.StatusFailed {
+			label = "说明"
+		}
+		n.Fields = append(n.Fields, NotificationField{label, errMessage})
+	}
+	n.Fields = append(n.Fields, NotificationField{"执行ID", execution.ID})
+	return n
+}
+
+// NotifySkipped 定时触发因并发限制被跳过时通知
+func (s *NotifierService) NotifySkipped(task *models.Task, reason error) {
+	s.notifyEvent(task, models.NotifyEventSkipped, NotifyTemplateData{Error: reason.Error()}, func() *Notification {
+		return &Notification{
+			Title: "⏭️ 定时触发被跳过",
+			Level: NotificationLevelWarning,
+			Fields: []NotificationField{
+				{"任务名称", task.Name},
+				{"原因", reason.Error()},
+				{"时间", NowBeijing().Format("2006-01-02 15:04:05")},
+			},
+			Task: task,
+		}
+	})
+}
+
+// WatchLongRunning 按任务的 long_running 规则在执行期间计时，超过阈值时通知
+// 返回的 stop 需在执行结束后调用
+func (s *NotifierService) WatchLongRunning(task *models.Task) (stop func()) {
+	var timers []*time.Timer
+	startedAt := NowBeijing()
+	for _, rule := range rulesFor(task, models.NotifyEventLongRunning) {
+		if rule.ThresholdMinutes <= 0 {
+			continue
+		}
+		rule := rule
+		timers = append(timers, time.AfterFunc(time.Duration(rule.ThresholdMinutes)*time.Minute, func() {
+			s.sendLongRunning(task, rule, startedAt)
+		}))
+	}
+	return func() {
+		for _, timer := range timers {
+			timer.Stop()
+		}
+	}
+}
+
+// sendLongRunning 发送运行时间过长通知（仅发送到触发的那条规则）
+func (s *NotifierService) sendLongRunning(task *models.Task, rule models.NotifyRule, startedAt time.Time) {
+	elapsed := time.Since(startedAt).Round(time.Minute)
+	data := NotifyTemplateData{Event: models.NotifyEventLongRunning, Task: task, Duration: elapsed.String()}
+	s.sendRule(rule, &Notification{
+		Title: "🐢 脚本运行时间过长",
+		Level: NotificationLevelWarning,
+		Fields: []NotificationField{
+			{"任务名称", task.Name},
+			{"脚本路径", task.ScriptPath},
+			{"已运行", elapsed.String()},
+			{"阈值", fmt.Sprintf("%d 分钟", rule.ThresholdMinutes)},
+			{"开始时间", startedAt.Format("2006-01-02 15:04:05")},
+		},
+		Task: task,
+	}, data)
+}
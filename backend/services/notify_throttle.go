@@ -0,0 +1,278 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"scriptguard/backend/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 告警节流参数
+const (
+	defaultNotifyDedupWindow = 30 * time.Minute
+	channelQueueSize         = 100 // 每个渠道待发送队列容量
+)
+
+// dedupEntry 同一指纹告警的去重状态
+type dedupEntry struct {
+	taskID     string
+	event      string
+	message    string    // 最近一次的错误信息（用于汇总）
+	firstAt    time.Time // 本轮首次出现
+	lastSentAt time.Time // 最近一次实际发送
+	lastSeenAt time.Time // 最近一次出现
+	count      int       // 本轮出现次数（含已发送的）
+	suppressed int       // 最近一次发送后被去重、尚未汇报的次数
+}
+
+// suppressedAlert 被去重且尚未汇报的告警汇总
+type suppressedAlert struct {
+	TaskID     string
+	Event      string
+	Message    string
+	FirstAt    time.Time
+	Count      int // 本轮累计次数
+	Suppressed int // 未单独发送的次数
+}
+
+// alertDeduper 按任务、事件与错误指纹去重：窗口内重复的告警只计数不发送，
+// 窗口过后再次出现时发送一条带累计次数的汇总；不再出现时由 Expired 取出未汇报的次数单独汇总
+type alertDeduper struct {
+	mu      sync.Mutex
+	window  time.Duration
+	entries map[string]*dedupEntry
+}
+
+func newAlertDeduper(window time.Duration) *alertDeduper {
+	return &alertDeduper{window: window, entries: make(map[string]*dedupEntry)}
+}
+
+// SetWindow 设置去重窗口（0 表示不去重）
+func (d *alertDeduper) SetWindow(window time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.window = window
+	if window <= 0 {
+		d.entries = make(map[string]*dedupEntry)
+	}
+}
+
+// Check 记录一次告警，返回是否发送；重复告警在窗口过后发送时 firstAt、count 为本轮累计
+func (d *alertDeduper) Check(taskID, event, message string, now time.Time) (send bool, firstAt time.Time, count int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.window <= 0 {
+		return true, now, 1
+	}
+
+	key := alertFingerprint(taskID, event, message)
+	e := d.entries[key]
+	// 超过窗口未再出现、且没有未汇报的重复时视为新一轮
+	if e != nil && now.Sub(e.lastSeenAt) > d.window && e.suppressed == 0 {
+		e = nil
+	}
+	if e == nil {
+		d.entries[key] = &dedupEntry{taskID: taskID, event: event, message: message,
+			firstAt: now, lastSentAt: now, lastSeenAt: now, count: 1}
+		d.prune(now)
+		return true, now, 1
+	}
+	e.count++
+	e.lastSeenAt = now
+	e.message = message
+	if now.Sub(e.lastSentAt) < d.window {
+		e.suppressed++
+		return false, e.firstAt, e.count
+	}
+	e.lastSentAt = now
+	e.suppressed = 0
+	return true, e.firstAt, e.count
+}
+
+// Expired 取出窗口已过、期间有重复未发送的告警（视为已汇报，同一轮后续重复重新计数）
+func (d *alertDeduper) Expired(now time.Time) []suppressedAlert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var alerts []suppressedAlert
+	for _, e := range d.entries {
+		if e.suppressed > 0 && now.Sub(e.lastSentAt) >= d.window {
+			alerts = append(alerts, e.summary())
+			e.lastSentAt = now
+			e.suppressed = 0
+		}
+	}
+	return alerts
+}
+
+// ResetTask 清除任务的去重状态（任务恢复成功后调用），返回尚未汇报的重复告警
+func (d *alertDeduper) ResetTask(taskID string) []suppressedAlert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var alerts []suppressedAlert
+	for key, e := range d.entries {
+		if e.taskID != taskID {
+			continue
+		}
+		if e.suppressed > 0 {
+			alerts = append(alerts, e.summary())
+		}
+		delete(d.entries, key)
+	}
+	return alerts
+}
+
+func (e *dedupEntry) summary() suppressedAlert {
+	return suppressedAlert{
+		TaskID:     e.taskID,
+		Event:      e.event,
+		Message:    e.message,
+		FirstAt:    e.firstAt,
+		Count:      e.count,
+		Suppressed: e.suppressed,
+	}
+}
+
+// prune 清理已过期且没有未汇报重复的状态（调用方需持有锁）
+func (d *alertDeduper) prune(now time.Time) {
+	for key, e := range d.entries {
+		if now.Sub(e.lastSeenAt) > d.window && e.suppressed == 0 {
+			delete(d.entries, key)
+		}
+	}
+}
+
+// volatilePattern 错误信息中随执行变化的部分（数字、十六进制串），计算指纹前统一替换
+var volatilePattern = regexp.MustCompile(`0x[0-9a-fA-F]+|[0-9a-fA-F]{8,}|\d+`)
+
+// alertFingerprint 告警指纹：任务 + 事件 + 归一化后的错误信息
+func alertFingerprint(taskID, event, message string) string {
+	normalized := volatilePattern.ReplaceAllString(strings.ToLower(strings.TrimSpace(message)), "#")
+	sum := sha1.Sum([]byte(normalized))
+	return taskID + "|" + event + "|" + hex.EncodeToString(sum[:8])
+}
+
+// dedupable 参与去重的事件（成功类事件每次都发送）
+func dedupable(event string) bool {
+	switch event {
+	case models.NotifyEventSuccess, models.NotifyEventRecovery:
+		return false
+	}
+	return true
+}
+
+// repeatSummary 重复告警的汇总说明
+func repeatSummary(event string, firstAt time.Time, count int) string {
+	verb := "重复出现"
+	switch event {
+	case models.NotifyEventFailure, models.NotifyEventFirstFailure, models.NotifyEventTimeout:
+		verb = "仍在失败"
+	}
+	return fmt.Sprintf("%s（自 %s 以来共 %d 次）", verb, firstAt.Format("15:04"), count)
+}
+
+// RateLimited 有发送频率限制的渠道（如钉钉、企业微信机器人每分钟 20 条）
+type RateLimited interface {
+	RateLimit() (n int, per time.Duration)
+}
+
+// rateWindow 渠道的发送时间窗口（滑动窗口）
+// 重新加载渠道时按渠道 ID 沿用，排空中的旧发送器与新发送器共用，合计不超过频率限制
+type rateWindow struct {
+	mu     sync.Mutex
+	sentAt []time.Time // 最近的发送时间（含已预占的未来时间）
+}
+
+// reserve 预占一次发送，返回需要等待的时长
+func (w *rateWindow) reserve(limit int, per time.Duration, now time.Time) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.sentAt) > 0 && now.Sub(w.sentAt[0]) >= per {
+		w.sentAt = w.sentAt[1:]
+	}
+	at := now
+	if len(w.sentAt) >= limit {
+		at = w.sentAt[len(w.sentAt)-limit].Add(per)
+	}
+	w.sentAt = append(w.sentAt, at)
+	return at.Sub(now)
+}
+
+// channelSender 渠道的排队发送器：按渠道自身的频率限制依次投递发件箱中的告警，超出限制时等待而不是被对方丢弃
+type channelSender struct {
+	name    string
+	deliver func(row *models.Notification)
+	limit   int
+	per     time.Duration
+	window  *rateWindow
+
+	mu     sync.Mutex
+	queue  chan *models.Notification
+	closed bool
+}
+
+// newChannelSender 创建渠道的发送器，deliver 负责发送并记录结果
+// window 为该渠道此前的发送窗口（首次加载时为空）
+func newChannelSender(name string, channel Channel, window *rateWindow, deliver func(row *models.Notification)) *channelSender {
+	if window == nil {
+		window = &rateWindow{}
+	}
+	s := &channelSender{
+		name:    name,
+		deliver: deliver,
+		window:  window,
+		queue:   make(chan *models.Notification, channelQueueSize),
+	}
+	if rl, ok := channel.(RateLimited); ok {
+		s.limit, s.per = rl.RateLimit()
+	}
+	go s.run()
+	return s
+}
+
+// Enqueue 加入发送队列，队列已满或发送器已关闭时返回 false
+func (s *channelSender) Enqueue(row *models.Notification) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	select {
+	case s.queue <- row:
+		return true
+	default:
+		log.Printf("渠道 %s 待发送告警过多，稍后重试: %s", s.name, row.Title)
+		return false
+	}
+}
+
+// Close 停止接收，已排队的告警发送完后退出
+func (s *channelSender) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+}
+
+func (s *channelSender) run() {
+	for row := range s.queue {
+		s.wait()
+		s.deliver(row)
+	}
+}
+
+// wait 按频率限制等待（滑动窗口）
+func (s *channelSender) wait() {
+	if s.limit <= 0 {
+		return
+	}
+	if d := s.window.reserve(s.limit, s.per, time.Now()); d > 0 {
+		time.Sleep(d)
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertDeduper(t *testing.T) {
+	const window = 10 * time.Minute
+	type check struct {
+		at        time.Duration // 相对起始时间
+		message   string
+		wantSend  bool
+		wantCount int
+	}
+	tests := []struct {
+		name           string
+		checks         []check
+		expireAt       time.Duration // >0 时在该时间取出过期的汇总
+		wantExpired    int           // 取出的汇总条数
+		wantSuppressed int           // 第一条汇总中未单独发送的次数
+	}{
+		{
+			name: "窗口内重复只计数",
+			checks: []check{
+				{0, "exit 1", true, 1},
+				{time.Minute, "exit 2", false, 2},
+				{2 * time.Minute, "exit 3", false, 3},
+			},
+		},
+		{
+			name: "不同错误分别发送",
+			checks: []check{
+				{0, "连接超时", true, 1},
+				{time.Minute, "文件不存在", true, 1},
+			},
+		},
+		{
+			name: "窗口过后仍在失败时发送汇总",
+			checks: []check{
+				{0, "exit 1", true, 1},
+				{5 * time.Minute, "exit 1", false, 2},
+				{11 * time.Minute, "exit 1", true, 3},
+			},
+		},
+		{
+			name: "未汇报的重复在超过窗口后再次出现时并入汇总",
+			checks: []check{
+				{0, "exit 1", true, 1},
+				{time.Minute, "exit 1", false, 2},
+				{30 * time.Minute, "exit 1", true, 3},
+			},
+		},
+		{
+			name: "没有重复时超过窗口视为新一轮",
+			checks: []check{
+				{0, "exit 1", true, 1},
+				{30 * time.Minute, "exit 1", true, 1},
+			},
+		},
+		{
+			name: "窗口过后不再出现时取出未汇报的次数",
+			checks: []check{
+				{0, "exit 1", true, 1},
+				{time.Minute, "exit 1", false, 2},
+				{2 * time.Minute, "exit 1", false, 3},
+			},
+			expireAt:       window,
+			wantExpired:    1,
+			wantSuppressed: 2,
+		},
+		{
+			name: "窗口未过不取出",
+			checks: []check{
+				{0, "exit 1", true, 1},
+				{time.Minute, "exit 1", false, 2},
+			},
+			expireAt:    window - time.Second,
+			wantExpired: 0,
+		},
+		{
+			name: "没有被去重的告警不取出",
+			checks: []check{
+				{0, "exit 1", true, 1},
+			},
+			expireAt:    time.Hour,
+			wantExpired: 0,
+		},
+	}
+
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, BeijingLocation)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newAlertDeduper(window)
+			for i, c := range tt.checks {
+				send, _, count := d.Check("task", "failure", c.message, start.Add(c.at))
+				if send != c.wantSend || count != c.wantCount {
+					t.Fatalf("第 %d 次 Check() = (%v, %d), want (%v, %d)", i+1, send, count, c.wantSend, c.wantCount)
+				}
+			}
+			if tt.expireAt == 0 {
+				return
+			}
+			alerts := d.Expired(start.Add(tt.expireAt))
+			if len(alerts) != tt.wantExpired {
+				t.Fatalf("Expired() 返回 %d 条, want %d", len(alerts), tt.wantExpired)
+			}
+			if len(alerts) > 0 && alerts[0].Suppressed != tt.wantSuppressed {
+				t.Errorf("Suppressed = %d, want %d", alerts[0].Suppressed, tt.wantSuppressed)
+			}
+			// 已汇报的次数不会重复取出
+			if again := d.Expired(start.Add(tt.expireAt + window)); len(alerts) > 0 && len(again) != 0 {
+				t.Errorf("再次 Expired() 返回 %d 条, want 0", len(again))
+			}
+		})
+	}
+}
+
+func TestAlertDeduperResetTask(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, BeijingLocation)
+	d := newAlertDeduper(10 * time.Minute)
+	d.Check("a", "failure", "exit 1", start)
+	d.Check("a", "failure", "exit 1", start.Add(time.Minute))
+	d.Check("a", "timeout", "执行超时", start)
+	d.Check("b", "failure", "exit 1", start)
+	d.Check("b", "failure", "exit 1", start.Add(time.Minute))
+
+	alerts := d.ResetTask("a")
+	if len(alerts) != 1 || alerts[0].TaskID != "a" || alerts[0].Suppressed != 1 {
+		t.Fatalf("ResetTask(a) = %+v, want 1 条未汇报的 failure", alerts)
+	}
+	// 恢复后重新计数，其他任务不受影响
+	if send, _, count := d.Check("a", "failure", "exit 1", start.Add(2*time.Minute)); !send || count != 1 {
+		t.Errorf("恢复后 Check(a) = (%v, %d), want (true, 1)", send, count)
+	}
+	if send, _, count := d.Check("b", "failure", "exit 1", start.Add(2*time.Minute)); send || count != 3 {
+		t.Errorf("Check(b) = (%v, %d), want (false, 3)", send, count)
+	}
+}
+
+func TestAlertDeduperDisabled(t *testing.T) {
+	d := newAlertDeduper(0)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if send, _, count := d.Check("a", "failure", "exit 1", now); !send || count != 1 {
+			t.Fatalf("Check() = (%v, %d), want (true, 1)", send, count)
+		}
+	}
+}
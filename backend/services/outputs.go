@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"scriptguard/backend/database"
+	"scriptguard/backend/models"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gorm.io/gorm"
+)
+
+// 执行时注入脚本的环境变量
+const (
+	envOutputFile  = "SG_OUTPUT_FILE"  // 脚本可向该文件写入 key=value 行或 JSON 对象来发布输出值
+	envExecutionID = "SG_EXECUTION_ID" // 当前执行 ID
+	envTaskID      = "SG_TASK_ID"      // 当前任务 ID
+)
+
+// maxOutputFileBytes 输出文件大小上限（超出视为误用，不解析）
+const maxOutputFileBytes = 1024 * 1024
+
+// readOutputFile 读取脚本写入的输出文件（文件不存在或为空时返回 nil）
+// 支持 JSON 对象，或每行一个 key=value（# 开头为注释，值可用双引号包裹）
+func readOutputFile(path string) (map[string]string, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxOutputFileBytes {
+		return nil, fmt.Errorf("输出文件超过 %d 字节", maxOutputFileBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("\xef\xbb\xbf"))
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	values := make(map[string]string)
+	if data[0] == '{' {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var obj map[string]any
+		if err := decoder.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("解析 JSON 输出失败: %w", err)
+		}
+		for key, value := range obj {
+			values[key] = logFieldString(value)
+		}
+		return values, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxOutputFileBytes)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("第 %d 行格式错误，应为 key=value", lineNo)
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
+
+// MergeOutputs 合并输出文件中的输出值（同名时覆盖协议指令上报的值）
+func (r *executionReport) MergeOutputs(values map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mergeOutputs(values)
+}
+
+// LaunchData 启动模板中可引用的数据
+type LaunchData struct {
+	TaskID      string
+	TaskName    string
+	ExecutionID string
+}
+
+// launchFuncs 启动模板函数（校验时使用占位实现，只检查语法与函数名）
+func launchFuncs(lookup func(taskRef, key string) (string, error)) template.FuncMap {
+	return template.FuncMap{
+		// output 引用上游任务（名称或 ID）最近一次成功执行发布的输出值
+		"output": lookup,
+		// quote 为含空格的参数加双引号，值中的双引号写作两个双引号
+		// 仅用于拼接含空格的参数，不负责转义 cmd 元字符（上游输出中的元字符在渲染时即被拒绝）
+		"quote": func(s string) string {
+			return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+		},
+	}
+}
+
+// ValidateLaunchTemplates 校验任务参数与环境变量中的模板语法
+func ValidateLaunchTemplates(task *models.Task) error {
+	funcs := launchFuncs(func(string, string) (string, error) { return "", nil })
+	if _, err := template.New("args").Funcs(funcs).Parse(task.Args); err != nil {
+		return fmt.Errorf("脚本参数模板非法: %w", err)
+	}
+	for key, value := range task.Env {
+		if key == "" || strings.ContainsAny(key, "= \t") {
+			return fmt.Errorf("环境变量名非法: %q", key)
+		}
+		if _, err := template.New(key).Funcs(funcs).Parse(value); err != nil {
+			return fmt.Errorf("环境变量 %s 模板非法: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// cmdMetaChars cmd.exe 命令行中有特殊含义的字符（及换行）
+const cmdMetaChars = "&|<>^%\"\r\n"
+
+// lookupArgOutput 查询用于脚本参数的上游输出：参数拼接在 cmd /c 命令行中，
+// 含 cmd 元字符的值可能被上游脚本用来注入命令，直接拒绝
+func lookupArgOutput(taskRef, key string) (string, error) {
+	value, err := LookupTaskOutput(taskRef, key)
+	if err != nil {
+		return "", err
+	}
+	if strings.ContainsAny(value, cmdMetaChars) {
+		return "", fmt.Errorf("上游任务 %s 的输出 %s 含有命令行特殊字符（& | < > ^ %% \" 或换行），不能用于脚本参数", taskRef, key)
+	}
+	return value, nil
+}
+
+// renderLaunch 渲染任务的脚本参数与环境变量（启动时解析上游任务输出）
+// 环境变量通过进程环境传递，不经过 cmd 解析；脚本参数中引用的输出不允许含 cmd 元字符
+func renderLaunch(task *models.Task, data LaunchData) (string, []string, error) {
+	render := func(name, text string, lookup func(taskRef, key string) (string, error)) (string, error) {
+		if !strings.Contains(text, "{{") {
+			return text, nil
+		}
+		funcs := launchFuncs(lookup)
+		tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	args, err := render("args", task.Args, lookupArgOutput)
+	if err != nil {
+		return "", nil, fmt.Errorf("渲染脚本参数失败: %w", err)
+	}
+	env := make([]string, 0, len(task.Env))
+	for key, value := range task.Env {
+		rendered, err := render(key, value, LookupTaskOutput)
+		if err != nil {
+			return "", nil, fmt.Errorf("渲染环境变量 %s 失败: %w", key, err)
+		}
+		env = append(env, key+"="+rendered)
+	}
+	return strings.TrimSpace(args), env, nil
+}
+
+// LookupTaskOutput 查询任务（名称或 ID）最近一次成功（含带警告结束）执行发布的输出值
+func LookupTaskOutput(taskRef, key string) (string, error) {
+	db := database.GetDB()
+
+	var task models.Task
+	if err := db.Where("id = ? OR name = ?", taskRef, taskRef).First(&task).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("上游任务不存在: %s", taskRef)
+		}
+		return "", fmt.Errorf("查询上游任务失败: %w", err)
+	}
+
+	var execution models.Execution
+	// 带警告结束的执行同样视为成功
+	err := db.Where("task_id = ? AND status IN ? AND outputs IS NOT NULL", task.ID,
+		[]models.ExecutionStatus{models.StatusSuccess, models.StatusWarning}).
+		Order("start_time DESC").
+		First(&execution).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("上游任务 %s 尚无发布输出的成功执行", task.Name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("查询上游任务输出失败: %w", err)
+	}
+
+	// 引用未脱敏的原值；早于区分原值的执行只有 Outputs
+	outputs := execution.RawOutputs
+	if outputs == nil {
+		outputs = execution.Outputs
+	}
+	value, ok := outputs[key]
+	if !ok {
+		return "", fmt.Errorf("上游任务 %s 最近一次成功执行未发布输出 %s", task.Name, key)
+	}
+	return logFieldString(value), nil
+}
@@ -0,0 +1,480 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"scriptguard/backend/database"
+	"scriptguard/backend/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 分页常量
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+func normalizePageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
+
+// decodePageCursors 解析 Before/After 游标（至多指定一个）
+func decodePageCursors(before, after string) (*pageCursor, *pageCursor, error) {
+	if before != "" && after != "" {
+		return nil, nil, fmt.Errorf("before 与 after 不能同时指定")
+	}
+	b, err := decodeCursor(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := decodeCursor(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, a, nil
+}
+
+// ==================== 执行历史 ====================
+
+// executionFilter 执行历史筛选条件
+func executionFilter(db *gorm.DB, q models.ExecutionQuery) *gorm.DB {
+	query := db.Model(&models.Execution{})
+	if q.TaskID != "" {
+		query = query.Where("task_id = ?", q.TaskID)
+	}
+	if len(q.Statuses) > 0 {
+		query = query.Where("status IN ?", q.Statuses)
+	}
+	if q.Start != nil {
+		query = query.Where("start_time >= ?", q.Start.In(BeijingLocation))
+	}
+	if q.End != nil {
+		query = query.Where("start_time < ?", q.End.In(BeijingLocation))
+	}
+	return query
+}
+
+// ListExecutions 键集分页查询执行历史（按 start_time, id 倒序）
+func ListExecutions(q models.ExecutionQuery) (*models.ExecutionPage, error) {
+	limit := normalizePageSize(q.Limit)
+	before, after, err := decodePageCursors(q.Before, q.After)
+	if err != nil {
+		return nil, err
+	}
+	db := database.GetDB()
+
+	var total int64
+	if err := executionFilter(db, q).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	older := func(query *gorm.DB, c *pageCursor) *gorm.DB {
+		return query.Where("(start_time < ? OR (start_time = ? AND id < ?))", c.Time, c.Time, c.ID).
+			Order("start_time DESC, id DESC")
+	}
+	newer := func(query *gorm.DB, c *pageCursor) *gorm.DB {
+		return query.Where("(start_time > ? OR (start_time = ? AND id > ?))", c.Time, c.Time, c.ID).
+			Order("start_time ASC, id ASC")
+	}
+	exists := func(scope func(*gorm.DB, *pageCursor) *gorm.DB, e models.Execution) (bool, error) {
+		var ids []string
+		err := scope(executionFilter(db, q), &pageCursor{Time: e.StartTime, ID: e.ID}).Limit(1).Pluck("id", &ids).Error
+		return len(ids) > 0, err
+	}
+
+	query := executionFilter(db, q)
+	switch {
+	case after != nil:
+		query = newer(query, after)
+	case before != nil:
+		query = older(query, before)
+	default:
+		query = query.Order("start_time DESC, id DESC")
+	}
+
+	var items []models.Execution
+	if err := query.Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	more := len(items) > limit
+	if more {
+		items = items[:limit]
+	}
+	if after != nil {
+		reverseExecutions(items)
+	}
+
+	page := &models.ExecutionPage{Items: items, Total: total}
+	if len(items) == 0 {
+		return page, nil
+	}
+	newest, oldest := items[0], items[len(items)-1]
+	page.AfterCursor = encodeCursor(newest.StartTime, newest.ID)
+	page.BeforeCursor = encodeCursor(oldest.StartTime, oldest.ID)
+	if after != nil {
+		page.HasAfter = more
+		page.HasBefore, err = exists(older, oldest)
+	} else {
+		page.HasBefore = more
+		if before != nil {
+			page.HasAfter, err = exists(newer, newest)
+		}
+	}
+	return page, err
+}
+
+func reverseExecutions(items []models.Execution) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
+
+// ==================== 日志 ====================
+
+// validateLogQuery 校验日志查询条件
+func validateLogQuery(q models.LogQuery) error {
+	for key := range q.Fields {
+		if key == "" || strings.ContainsAny(key, `"\`) {
+			return fmt.Errorf("非法的字段名: %q", key)
+		}
+	}
+	return nil
+}
+
+// logFilter 日志筛选条件（数据库后端）
+func logFilter(db *gorm.DB, q models.LogQuery) *gorm.DB {
+	query := db.Model(&models.Log{})
+	if q.ExecutionID != "" {
+		query = query.Where("execution_id = ?", q.ExecutionID)
+	} else if q.TaskID != "" {
+		query = query.Where("task_id = ?", q.TaskID)
+	}
+	if len(q.Levels) > 0 {
+		query = query.Where("level IN ?", q.Levels)
+	}
+	for key, value := range q.Fields {
+		query = query.Where("CAST(json_extract(fields, ?) AS TEXT) = ?", `$."`+key+`"`, value)
+	}
+	if q.Start != nil {
+		query = query.Where("timestamp >= ?", q.Start.In(BeijingLocation))
+	}
+	if q.End != nil {
+		query = query.Where("timestamp < ?", q.End.In(BeijingLocation))
+	}
+	return query
+}
+
+// logMatches 日志筛选条件（文件后端，内存中判断）
+func logMatches(l *models.Log, q models.LogQuery) bool {
+	if len(q.Levels) > 0 {
+		found := false
+		for _, level := range q.Levels {
+			if l.Level == level {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for key, value := range q.Fields {
+		v, ok := l.Fields[key]
+		if !ok || logFieldString(v) != value {
+			return false
+		}
+	}
+	if q.Start != nil && l.Timestamp.Before(*q.Start) {
+		return false
+	}
+	if q.End != nil && !l.Timestamp.Before(*q.End) {
+		return false
+	}
+	return true
+}
+
+// compareLogKey 比较日志与游标位置：<0 表示更早，>0 表示更新
+func compareLogKey(l *models.Log, c *pageCursor) int {
+	if l.Timestamp.Before(c.Time) {
+		return -1
+	}
+	if l.Timestamp.After(c.Time) {
+		return 1
+	}
+	switch {
+	case l.ID < c.ID:
+		return -1
+	case l.ID > c.ID:
+		return 1
+	}
+	return 0
+}
+
+// logFileMetas 查询满足执行/任务与时间条件的日志文件元数据
+func logFileMetas(q models.LogQuery, cursor *pageCursor, older bool) ([]models.LogFile, error) {
+	query := database.GetDB().Model(&models.LogFile{})
+	if q.ExecutionID != "" {
+		query = query.Where("execution_id = ?", q.ExecutionID)
+	} else if q.TaskID != "" {
+		query = query.Where("task_id = ?", q.TaskID)
+	}
+	if q.Start != nil {
+		query = query.Where("last_time >= ?", q.Start.In(BeijingLocation))
+	}
+	if q.End != nil {
+		query = query.Where("first_time < ?", q.End.In(BeijingLocation))
+	}
+	if cursor != nil {
+		if older {
+			query = query.Where("first_time <= ?", cursor.Time)
+		} else {
+			query = query.Where("last_time >= ?", cursor.Time)
+		}
+	}
+	if older {
+		query = query.Order("last_time DESC")
+	} else {
+		query = query.Order("first_time ASC")
+	}
+
+	var metas []models.LogFile
+	err := query.Find(&metas).Error
+	return metas, err
+}
+
+// chunkInRange 分块时间区间是否可能包含满足条件的日志
+func chunkInRange(c models.LogChunk, q models.LogQuery, cursor *pageCursor, older bool) bool {
+	if q.Start != nil && c.End.Before(*q.Start) {
+		return false
+	}
+	if q.End != nil && !c.Start.Before(*q.End) {
+		return false
+	}
+	if cursor != nil {
+		if older && c.Start.After(cursor.Time) {
+			return false
+		}
+		if !older && c.End.Before(cursor.Time) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortLogsDirection 按方向排序：older 为倒序（最新在前），否则正序
+func sortLogsDirection(logs []models.Log, older bool) {
+	sortLogs(logs)
+	if older {
+		for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+			logs[i], logs[j] = logs[j], logs[i]
+		}
+	}
+}
+
+// scanLogFiles 从文件后端按方向收集至多 n 条满足条件的日志
+// 文件与分块均按时间边界排序，已凑够 n 条且剩余部分整体落在第 n 条之外时提前停止
+func scanLogFiles(q models.LogQuery, cursor *pageCursor, older bool, n int) ([]models.Log, error) {
+	metas, err := logFileMetas(q, cursor, older)
+	if err != nil {
+		return nil, err
+	}
+
+	// beyond 判断某个时间区间是否整体落在当前第 n 条之外（无需再读）
+	collected := make([]models.Log, 0)
+	beyond := func(start, end time.Time) bool {
+		if len(collected) < n {
+			return false
+		}
+		edge := collected[n-1].Timestamp
+		if older {
+			return end.Before(edge)
+		}
+		return start.After(edge)
+	}
+
+	for i := range metas {
+		meta := &metas[i]
+		if beyond(meta.FirstTime, meta.LastTime) {
+			break
+		}
+
+		chunks := make([]models.LogChunk, 0, len(meta.Chunks))
+		for _, c := range meta.Chunks {
+			if chunkInRange(c, q, cursor, older) {
+				chunks = append(chunks, c)
+			}
+		}
+		// 溢出补录的块可能早于前面的块，按时间边界排序后再读
+		sort.SliceStable(chunks, func(a, b int) bool {
+			if older {
+				return chunks[a].End.After(chunks[b].End)
+			}
+			return chunks[a].Start.Before(chunks[b].Start)
+		})
+
+		for _, c := range chunks {
+			if beyond(c.Start, c.End) {
+				break
+			}
+			entries, err := readLogChunks(meta.Path, []models.LogChunk{c})
+			if err != nil {
+				log.Printf("读取日志文件失败(execution_id=%s, path=%s): %v", meta.ExecutionID, meta.Path, err)
+				break
+			}
+			for j := range entries {
+				entry := &entries[j]
+				if !logMatches(entry, q) {
+					continue
+				}
+				if cursor != nil {
+					if cmp := compareLogKey(entry, cursor); (older && cmp >= 0) || (!older && cmp <= 0) {
+						continue
+					}
+				}
+				collected = append(collected, *entry)
+			}
+			sortLogsDirection(collected, older)
+			if len(collected) > n {
+				collected = collected[:n]
+			}
+		}
+	}
+	return collected, nil
+}
+
+// fetchLogs 合并数据库与文件后端，按方向取至多 n 条（older 时最新在前），并返回是否还有更多
+func fetchLogs(q models.LogQuery, cursor *pageCursor, older bool, n int) ([]models.Log, bool, error) {
+	query := logFilter(database.GetDB(), q)
+	if cursor != nil {
+		if older {
+			query = query.Where("(timestamp < ? OR (timestamp = ? AND id < ?))", cursor.Time, cursor.Time, cursor.ID)
+		} else {
+			query = query.Where("(timestamp > ? OR (timestamp = ? AND id > ?))", cursor.Time, cursor.Time, cursor.ID)
+		}
+	}
+	if older {
+		query = query.Order("timestamp DESC, id DESC")
+	} else {
+		query = query.Order("timestamp ASC, id ASC")
+	}
+
+	var logs []models.Log
+	if err := query.Limit(n + 1).Find(&logs).Error; err != nil {
+		return nil, false, err
+	}
+
+	fileLogs, err := scanLogFiles(q, cursor, older, n+1)
+	if err != nil {
+		return nil, false, err
+	}
+	logs = append(logs, fileLogs...)
+	sortLogsDirection(logs, older)
+
+	more := len(logs) > n
+	if more {
+		logs = logs[:n]
+	}
+	return logs, more, nil
+}
+
+// countLogs 统计满足条件的日志总数（文件后端仅在有筛选条件时才需解压计数）
+func countLogs(q models.LogQuery) (int64, error) {
+	var total int64
+	if err := logFilter(database.GetDB(), q).Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	metas, err := logFileMetas(q, nil, true)
+	if err != nil {
+		return 0, err
+	}
+	filtered := len(q.Levels) > 0 || len(q.Fields) > 0 || q.Start != nil || q.End != nil
+	for i := range metas {
+		meta := &metas[i]
+		if !filtered {
+			total += int64(meta.Lines)
+			continue
+		}
+		for _, c := range meta.Chunks {
+			if !chunkInRange(c, q, nil, true) {
+				continue
+			}
+			entries, err := readLogChunks(meta.Path, []models.LogChunk{c})
+			if err != nil {
+				log.Printf("读取日志文件失败(execution_id=%s, path=%s): %v", meta.ExecutionID, meta.Path, err)
+				break
+			}
+			for j := range entries {
+				if logMatches(&entries[j], q) {
+					total++
+				}
+			}
+		}
+	}
+	return total, nil
+}
+
+// ListLogs 键集分页查询日志（按 timestamp, id 正序返回），透明合并数据库与文件存储后端
+func ListLogs(q models.LogQuery) (*models.LogPage, error) {
+	limit := normalizePageSize(q.Limit)
+	if err := validateLogQuery(q); err != nil {
+		return nil, err
+	}
+	before, after, err := decodePageCursors(q.Before, q.After)
+	if err != nil {
+		return nil, err
+	}
+
+	older := after == nil
+	cursor := before
+	if after != nil {
+		cursor = after
+	}
+
+	items, more, err := fetchLogs(q, cursor, older, limit)
+	if err != nil {
+		return nil, err
+	}
+	if older {
+		// 倒序取回后翻转为正序
+		sortLogs(items)
+	}
+
+	total, err := countLogs(q)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.LogPage{Items: items, Total: total}
+	if len(items) == 0 {
+		return page, nil
+	}
+	first, last := items[0], items[len(items)-1]
+	page.BeforeCursor = encodeCursor(first.Timestamp, first.ID)
+	page.AfterCursor = encodeCursor(last.Timestamp, last.ID)
+
+	hasLogs := func(l models.Log, older bool) (bool, error) {
+		found, _, err := fetchLogs(q, &pageCursor{Time: l.Timestamp, ID: l.ID}, older, 1)
+		return len(found) > 0, err
+	}
+	if older {
+		page.HasBefore = more
+		if cursor != nil {
+			page.HasAfter, err = hasLogs(last, false)
+		}
+	} else {
+		page.HasAfter = more
+		page.HasBefore, err = hasLogs(first, true)
+	}
+	return page, err
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestNormalizePageSize(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{-1, defaultPageSize},
+		{0, defaultPageSize},
+		{1, 1},
+		{maxPageSize, maxPageSize},
+		{maxPageSize + 1, maxPageSize},
+	}
+	for _, tt := range tests {
+		if got := normalizePageSize(tt.limit); got != tt.want {
+			t.Errorf("normalizePageSize(%d) = %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		time time.Time
+		id   string
+	}{
+		{"北京时间", time.Date(2024, 5, 1, 8, 30, 0, 0, BeijingLocation), "a1b2c3"},
+		{"UTC 时间转换为北京时间", time.Date(2024, 5, 1, 0, 30, 0, 0, time.UTC), "exec-1"},
+		{"保留纳秒", time.Date(2024, 12, 31, 23, 59, 59, 999999999, BeijingLocation), "x"},
+		{"跨年边界", time.Date(2023, 12, 31, 16, 0, 0, 0, time.UTC), "y"},
+		{"ID 含特殊字符", time.Date(2024, 1, 1, 0, 0, 0, 1, BeijingLocation), `id/with+"quotes"&=?`},
+		{"ID 含中文", time.Date(2024, 1, 1, 0, 0, 0, 0, BeijingLocation), "任务-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := encodeCursor(tt.time, tt.id)
+			c, err := decodeCursor(s)
+			if err != nil {
+				t.Fatalf("decodeCursor(%q) 失败: %v", s, err)
+			}
+			if !c.Time.Equal(tt.time) {
+				t.Errorf("Time = %v, want %v", c.Time, tt.time)
+			}
+			if c.Time.Location() != BeijingLocation {
+				t.Errorf("Time 时区 = %v, want %v", c.Time.Location(), BeijingLocation)
+			}
+			if c.ID != tt.id {
+				t.Errorf("ID = %q, want %q", c.ID, tt.id)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	valid := encodeCursor(time.Date(2024, 5, 1, 8, 30, 0, 0, BeijingLocation), "abc")
+
+	tests := []struct {
+		name    string
+		input   string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "空字符串表示无游标", input: "", wantNil: true},
+		{name: "有效游标", input: valid},
+		{name: "非 base64", input: "!!!", wantErr: true},
+		{name: "带填充的标准 base64", input: base64.StdEncoding.EncodeToString([]byte(`{"t":"2024-05-01T08:30:00+08:00","id":"ab"}`)), wantErr: true},
+		{name: "被截断", input: valid[:len(valid)-3], wantErr: true},
+		{name: "非 JSON", input: raw("hello"), wantErr: true},
+		{name: "JSON 数组", input: raw(`["a"]`), wantErr: true},
+		{name: "时间格式非法", input: raw(`{"t":"yesterday","id":"a"}`), wantErr: true},
+		{name: "缺少 ID", input: raw(`{"t":"2024-05-01T08:30:00+08:00"}`), wantErr: true},
+		{name: "缺少时间", input: raw(`{"id":"a"}`), wantErr: true},
+		{name: "空对象", input: raw(`{}`), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := decodeCursor(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCursor(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (c == nil) != tt.wantNil {
+				t.Errorf("decodeCursor(%q) = %v, wantNil %v", tt.input, c, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestDecodePageCursors(t *testing.T) {
+	cursor := encodeCursor(time.Date(2024, 5, 1, 8, 30, 0, 0, BeijingLocation), "abc")
+
+	tests := []struct {
+		name       string
+		before     string
+		after      string
+		wantBefore bool
+		wantAfter  bool
+		wantErr    bool
+	}{
+		{name: "均未指定（第一页）"},
+		{name: "仅 before", before: cursor, wantBefore: true},
+		{name: "仅 after", after: cursor, wantAfter: true},
+		{name: "同时指定", before: cursor, after: cursor, wantErr: true},
+		{name: "before 非法", before: "!!!", wantErr: true},
+		{name: "after 非法", after: "!!!", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after, err := decodePageCursors(tt.before, tt.after)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodePageCursors() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (before != nil) != tt.wantBefore {
+				t.Errorf("before = %v, want present %v", before, tt.wantBefore)
+			}
+			if (after != nil) != tt.wantAfter {
+				t.Errorf("after = %v, want present %v", after, tt.wantAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"scriptguard/backend/database"
+	"scriptguard/backend/models"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// 资源池限制
+const (
+	maxPoolNameLength = 64
+	maxTaskPools      = 10
+)
+
+// ValidatePool 校验并归一化资源池
+func ValidatePool(pool *models.Pool) error {
+	pool.Name = strings.TrimSpace(pool.Name)
+	switch {
+	case pool.Name == "":
+		return fmt.Errorf("资源池名称不能为空")
+	case utf8.RuneCountInString(pool.Name) > maxPoolNameLength:
+		return fmt.Errorf("资源池名称不能超过 %d 个字符", maxPoolNameLength)
+	case pool.MaxRunning < 1:
+		return fmt.Errorf("资源池最大并发数至少为 1")
+	}
+	return nil
+}
+
+// ValidateTaskPools 校验任务引用的资源池均已存在，并去除空白与重复项
+func ValidateTaskPools(task *models.Task) error {
+	var names models.StringList
+	for _, name := range task.Pools {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) > maxTaskPools {
+		return fmt.Errorf("每个任务最多加入 %d 个资源池", maxTaskPools)
+	}
+	task.Pools = names
+	if len(names) == 0 {
+		return nil
+	}
+
+	var count int64
+	if err := database.GetDB().Model(&models.Pool{}).Where("name IN ?", []string(names)).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(names) {
+		return fmt.Errorf("任务引用了不存在的资源池")
+	}
+	return nil
+}
+
+// TasksUsingPool 查询引用资源池的任务名称
+func TasksUsingPool(name string) ([]string, error) {
+	var tasks []models.Task
+	// 先按 JSON 文本粗筛，再精确比对
+	err := database.GetDB().
+		Select("id", "name", "pools").
+		Where("pools LIKE ?", "%"+name+"%").
+		Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, task := range tasks {
+		if slices.Contains(task.Pools, name) {
+			names = append(names, task.Name)
+		}
+	}
+	return names, nil
+}
+
+// TasksExceedingCapacity 查询在给定最大并发数下无法通过排队校验（权重超过最大并发数）的任务名称
+// pool 非空时只检查引用该资源池的任务
+func TasksExceedingCapacity(maxConcurrency int, pool string) ([]string, error) {
+	var tasks []models.Task
+	query := database.GetDB().Select("id", "name", "priority", "weight", "pools")
+	if pool != "" {
+		query = query.Where("pools LIKE ?", "%"+pool+"%")
+	}
+	if err := query.Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	var names []string
+	for i := range tasks {
+		if pool != "" && !slices.Contains(tasks[i].Pools, pool) {
+			continue
+		}
+		if ValidateQueueSettings(&tasks[i], maxConcurrency) != nil {
+			names = append(names, tasks[i].Name)
+		}
+	}
+	return names, nil
+}
+
+// LoadPools 从数据库加载资源池配置到执行器
+func (s *ExecutorService) LoadPools() error {
+	var pools []models.Pool
+	if err := database.GetDB().Find(&pools).Error; err != nil {
+		return fmt.Errorf("加载资源池失败: %w", err)
+	}
+	limits := make(map[string]int, len(pools))
+	for _, pool := range pools {
+		limits[pool.Name] = pool.MaxRunning
+	}
+	s.limiter.SetPools(limits)
+	return nil
+}
+
+// PoolUsage 资源池当前已占用的名额
+func (s *ExecutorService) PoolUsage(name string) int {
+	return s.limiter.PoolUsage(name)
+}
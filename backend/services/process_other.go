@@ -0,0 +1,31 @@
+//go:build !windows
+
+package services
+
+import (
+	"os"
+	"syscall"
+)
+
+// newProcAttr 子进程属性：使用独立进程组，便于整体结束
+func newProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
+
+// newScriptProcAttr 脚本进程属性：与 newProcAttr 相同（资源限制在启动后施加）
+func newScriptProcAttr() *syscall.SysProcAttr {
+	return newProcAttr()
+}
+
+// resumeProcess 非 Windows 平台进程不以挂起状态启动，无需恢复
+func resumeProcess(_ *os.Process) error {
+	return nil
+}
+
+// killProcessTree 结束进程所在的整个进程组
+func killProcessTree(p *os.Process) error {
+	if err := syscall.Kill(-p.Pid, syscall.SIGKILL); err != nil {
+		return p.Kill()
+	}
+	return nil
+}
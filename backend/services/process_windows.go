@@ -0,0 +1,74 @@
+//go:build windows
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// newProcAttr 子进程属性：隐藏控制台窗口
+func newProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{HideWindow: true}
+}
+
+// newScriptProcAttr 脚本进程属性：以挂起状态启动，待加入作业对象后再由 resumeProcess 恢复，
+// 避免进程在资源限制生效前派生子进程
+func newScriptProcAttr() *syscall.SysProcAttr {
+	attr := newProcAttr()
+	attr.CreationFlags |= windows.CREATE_SUSPENDED
+	return attr
+}
+
+// resumeProcess 恢复以挂起状态启动的进程（恢复其所有线程）
+func resumeProcess(p *os.Process) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return fmt.Errorf("枚举线程失败: %w", err)
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var entry windows.ThreadEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	resumed := 0
+	var errs []error
+	for err = windows.Thread32First(snapshot, &entry); err == nil; err = windows.Thread32Next(snapshot, &entry) {
+		if entry.OwnerProcessID != uint32(p.Pid) {
+			continue
+		}
+		thread, err := windows.OpenThread(windows.THREAD_SUSPEND_RESUME, false, entry.ThreadID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := windows.ResumeThread(thread); err != nil {
+			errs = append(errs, err)
+		} else {
+			resumed++
+		}
+		windows.CloseHandle(thread)
+	}
+	if resumed == 0 {
+		errs = append(errs, errors.New("未找到可恢复的线程"))
+		return fmt.Errorf("恢复进程失败: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// killProcessTree 结束进程及其所有子进程（cmd /c → conda → python）
+func killProcessTree(p *os.Process) error {
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid))
+	kill.SysProcAttr = newProcAttr()
+	if err := kill.Run(); err != nil {
+		// taskkill 失败时至少结束直接子进程
+		return p.Kill()
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"scriptguard/backend/models"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 脚本上报协议：脚本在 stdout/stderr 中输出以 "::sg " 开头的指令行，执行器拦截后不计入普通日志
+//
+//	::sg progress 42/100 [说明]   上报进度（也支持 42% 或单独的百分比数字）
+//	::sg metric rows=1234 [k=v…] 上报命名指标（同名覆盖，数值按数字存储）
+//	::sg output key=value         发布输出值（值可含空格，也可用双引号包裹）
+const protocolPrefix = "::sg "
+
+// 协议指令
+const (
+	protocolProgress = "progress"
+	protocolMetric   = "metric"
+	protocolOutput   = "output"
+)
+
+// 实时日志流中的上报事件级别（不落库）
+const (
+	StreamEventProgress = "progress"
+	StreamEventMetric   = "metric"
+	StreamEventFinished = "finished" // 执行结束（Content 为最终状态），前端据此清除实时进度
+)
+
+// protocolCommand 解析后的协议指令
+type protocolCommand struct {
+	Name    string
+	Current float64
+	Total   float64 // 0 表示仅上报了百分比
+	Percent float64
+	Message string
+	Values  map[string]string
+}
+
+// parseProtocolLine 解析协议指令行，不是合法指令时返回 nil（按普通输出处理）
+func parseProtocolLine(line string) *protocolCommand {
+	if !strings.HasPrefix(line, protocolPrefix) {
+		return nil
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(line, protocolPrefix))
+	name, args, _ := strings.Cut(rest, " ")
+	args = strings.TrimSpace(args)
+
+	switch name {
+	case protocolProgress:
+		return parseProgressCommand(args)
+	case protocolMetric:
+		values, ok := parseKeyValues(args)
+		if !ok {
+			return nil
+		}
+		return &protocolCommand{Name: protocolMetric, Values: values}
+	case protocolOutput:
+		key, value, ok := strings.Cut(args, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+		return &protocolCommand{Name: protocolOutput, Values: map[string]string{key: value}}
+	}
+	return nil
+}
+
+// parseProgressCommand 解析进度：42/100、42%、42
+func parseProgressCommand(args string) *protocolCommand {
+	value, message, _ := strings.Cut(args, " ")
+	cmd := &protocolCommand{Name: protocolProgress, Message: strings.TrimSpace(message)}
+
+	if current, total, ok := strings.Cut(value, "/"); ok {
+		c, ok1 := parseFiniteFloat(current)
+		t, ok2 := parseFiniteFloat(total)
+		if !ok1 || !ok2 || t <= 0 || c < 0 {
+			return nil
+		}
+		cmd.Current, cmd.Total = c, t
+		cmd.Percent = c / t * 100
+	} else {
+		p, ok := parseFiniteFloat(strings.TrimSuffix(value, "%"))
+		if !ok || p < 0 {
+			return nil
+		}
+		cmd.Current, cmd.Percent = p, p
+	}
+	cmd.Percent = min(cmd.Percent, 100)
+	return cmd
+}
+
+// parseFiniteFloat 解析有限数值：nan、inf 无法编码为 JSON，视为非数值
+func parseFiniteFloat(s string) (float64, bool) {
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, false
+	}
+	return n, true
+}
+
+// executionReport 单次执行中脚本上报的进度、指标与输出（stdout/stderr 并发写入）
+// 输出值同时保留原值（传给下游任务）与脱敏后的值（展示）
+type executionReport struct {
+	mu         sync.Mutex
+	redactor   *Redactor
+	command    *protocolCommand // 最近一次进度
+	metrics    models.JSONMap
+	outputs    models.JSONMap
+	rawOutputs models.JSONMap
+}
+
+// Apply 记录一条指令
+func (r *executionReport) Apply(cmd *protocolCommand) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch cmd.Name {
+	case protocolProgress:
+		r.command = cmd
+	case protocolMetric:
+		if r.metrics == nil {
+			r.metrics = models.JSONMap{}
+		}
+		// nan、inf 等非有限数值按字符串存储
+		for key, value := range cmd.Values {
+			if n, ok := parseFiniteFloat(value); ok {
+				r.metrics[key] = n
+			} else {
+				r.metrics[key] = value
+			}
+		}
+	case protocolOutput:
+		r.mergeOutputs(cmd.Values)
+	}
+}
+
+// mergeOutputs 合并输出值（调用方需持有锁）
+func (r *executionReport) mergeOutputs(values map[string]string) {
+	if r.outputs == nil {
+		r.outputs = models.JSONMap{}
+		r.rawOutputs = models.JSONMap{}
+	}
+	for key, value := range values {
+		r.outputs[key] = r.redactor.Redact(value)
+		r.rawOutputs[key] = value
+	}
+}
+
+// ApplyTo 写入执行记录
+func (r *executionReport) ApplyTo(execution *models.Execution) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cmd := r.command; cmd != nil {
+		percent := cmd.Percent
+		execution.Progress = &percent
+		execution.ProgressCurrent = cmd.Current
+		execution.ProgressTotal = cmd.Total
+		execution.ProgressMessage = cmd.Message
+	}
+	execution.Metrics = r.metrics
+	execution.Outputs = r.outputs
+	execution.RawOutputs = r.rawOutputs
+}
+
+// streamEvent 生成推送到实时日志流的上报事件
+func (cmd *protocolCommand) streamEvent(execution *models.Execution) (LogMessage, bool) {
+	msg := LogMessage{
+		ExecutionID: execution.ID,
+		TaskID:      execution.TaskID,
+		Timestamp:   NowBeijing(),
+	}
+	switch cmd.Name {
+	case protocolProgress:
+		msg.Level = StreamEventProgress
+		if cmd.Total > 0 {
+			msg.Content = fmt.Sprintf("%g/%g (%.1f%%)", cmd.Current, cmd.Total, cmd.Percent)
+		} else {
+			msg.Content = fmt.Sprintf("%.1f%%", cmd.Percent)
+		}
+		if cmd.Message != "" {
+			msg.Content += " " + cmd.Message
+		}
+		msg.Fields = map[string]any{
+			"current": cmd.Current,
+			"total":   cmd.Total,
+			"percent": cmd.Percent,
+			"message": cmd.Message,
+		}
+	case protocolMetric:
+		msg.Level = StreamEventMetric
+		fields := make(map[string]any, len(cmd.Values))
+		parts := make([]string, 0, len(cmd.Values))
+		for key, value := range cmd.Values {
+			fields[key] = value
+			parts = append(parts, key+"="+value)
+		}
+		msg.Content = strings.Join(parts, " ")
+		msg.Fields = fields
+	default:
+		// 输出值只记录在执行结果中，不推送
+		return msg, false
+	}
+	return msg, true
+}
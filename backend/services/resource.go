@@ -0,0 +1,243 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"scriptguard/backend/database"
+	"scriptguard/backend/models"
+	"sync"
+	"time"
+)
+
+// 资源采样参数
+const (
+	resourceSampleInterval = 2 * time.Second
+	maxResourceTrendLimit  = 1000
+)
+
+// resourceSample 一次采样得到的进程树累计值
+type resourceSample struct {
+	CPUTime    time.Duration // 累计 CPU 时间（用户态 + 内核态）
+	Memory     int64         // 当前内存占用（常驻内存 / 工作集）
+	ReadBytes  int64         // 累计读取字节
+	WriteBytes int64         // 累计写入字节
+}
+
+// resourceProbe 平台相关的进程树采样与资源限制实现
+type resourceProbe interface {
+	Sample() (resourceSample, error)
+	// MemoryLimitHit 进程退出后调用：是否触发过系统层面的内存限制（OOM / 作业内存上限）
+	MemoryLimitHit() bool
+	Close()
+}
+
+// resourceLimits 单次执行的资源限制（0 表示不限制）
+type resourceLimits struct {
+	MemoryBytes  int64
+	CPUPercent   int
+	MaxProcesses int
+	MaxOpenFiles int
+	Nice         int
+}
+
+// limitsFromTask 读取任务的资源限制
+func limitsFromTask(task *models.Task) resourceLimits {
+	return resourceLimits{
+		MemoryBytes:  int64(task.MaxMemoryMB) * 1024 * 1024,
+		CPUPercent:   task.CPULimitPercent,
+		MaxProcesses: task.MaxProcesses,
+		MaxOpenFiles: task.MaxOpenFiles,
+		Nice:         task.NiceLevel,
+	}
+}
+
+// IsZero 是否未设置任何限制
+func (l resourceLimits) IsZero() bool {
+	return l == resourceLimits{}
+}
+
+// ValidateResourceLimits 校验任务的资源限制
+func ValidateResourceLimits(task *models.Task) error {
+	switch {
+	case task.MaxMemoryMB < 0:
+		return fmt.Errorf("内存上限不能为负数")
+	case task.CPULimitPercent < 0 || task.CPULimitPercent > 100:
+		return fmt.Errorf("CPU 上限超出允许范围：0~100%%")
+	case task.MaxProcesses < 0:
+		return fmt.Errorf("进程数上限不能为负数")
+	case task.MaxOpenFiles < 0:
+		return fmt.Errorf("打开文件数上限不能为负数")
+	case task.NiceLevel < -20 || task.NiceLevel > 19:
+		return fmt.Errorf("调度优先级超出允许范围：-20~19")
+	}
+	return nil
+}
+
+// resourceMonitor 执行期间定时采样进程树资源占用，汇总峰值与均值
+type resourceMonitor struct {
+	probe   resourceProbe
+	process *os.Process
+	limits  resourceLimits
+	stop    chan struct{}
+	done    chan struct{}
+
+	memoryKilled bool // 采样发现超出内存上限并已终止进程
+
+	mu        sync.Mutex
+	last      resourceSample
+	lastAt    time.Time
+	samples   int
+	memSum    int64
+	memPeak   int64
+	cpuPeak   float64
+	startedAt time.Time
+}
+
+// newResourceMonitor 为已启动的进程应用资源限制并开始采样
+// 当前系统不支持采样时返回 nil（方法对 nil 安全）；部分限制未能生效时通过 error 返回原因，采样照常进行
+func newResourceMonitor(p *os.Process, limits resourceLimits) (*resourceMonitor, error) {
+	probe, err := newResourceProbe(p, limits)
+	if probe == nil {
+		if err == nil && !limits.IsZero() {
+			err = fmt.Errorf("当前系统不支持资源限制")
+		}
+		return nil, err
+	}
+	m := &resourceMonitor{
+		probe:     probe,
+		process:   p,
+		limits:    limits,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		startedAt: time.Now(),
+		lastAt:    time.Now(),
+	}
+	go m.run()
+	return m, err
+}
+
+func (m *resourceMonitor) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(resourceSampleInterval)
+	defer ticker.Stop()
+
+	m.sample(false)
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.sample(false)
+		}
+	}
+}
+
+// sample 采样一次并更新汇总；final 为进程退出后的补充采样，只更新累计值
+func (m *resourceMonitor) sample(final bool) {
+	s, err := m.probe.Sample()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 进程退出后累计值可能回落（子进程已被回收），只取单调部分
+	s.CPUTime = max(s.CPUTime, m.last.CPUTime)
+	s.ReadBytes = max(s.ReadBytes, m.last.ReadBytes)
+	s.WriteBytes = max(s.WriteBytes, m.last.WriteBytes)
+
+	if final {
+		m.last.CPUTime, m.last.ReadBytes, m.last.WriteBytes = s.CPUTime, s.ReadBytes, s.WriteBytes
+		return
+	}
+	if elapsed := now.Sub(m.lastAt); elapsed > 0 && m.samples > 0 {
+		percent := float64(s.CPUTime-m.last.CPUTime) / float64(elapsed) * 100
+		m.cpuPeak = max(m.cpuPeak, percent)
+	}
+	m.samples++
+	m.memSum += s.Memory
+	m.memPeak = max(m.memPeak, s.Memory)
+	m.last = s
+	m.lastAt = now
+
+	// 系统层面的限制无法覆盖全部情况（如 rlimit 只限制单进程），超出上限时直接终止进程树
+	if m.limits.MemoryBytes > 0 && s.Memory > m.limits.MemoryBytes && !m.memoryKilled {
+		m.memoryKilled = true
+		if err := killProcessTree(m.process); err != nil {
+			log.Printf("终止超出内存限制的进程失败(pid=%d): %v", m.process.Pid, err)
+		}
+	}
+}
+
+// Stop 结束采样（进程退出后调用），汇总写入执行记录
+// state 为进程退出状态，用于补齐最后一次采样之后的 CPU 时间
+func (m *resourceMonitor) Stop(execution *models.Execution, state *os.ProcessState) {
+	if m == nil {
+		if state != nil {
+			execution.CPUTimeMs = (state.UserTime() + state.SystemTime()).Milliseconds()
+		}
+		return
+	}
+	close(m.stop)
+	<-m.done
+	// 部分平台（如 Windows 作业对象）在进程退出后仍可查询累计值
+	m.sample(true)
+	memoryHit := m.probe.MemoryLimitHit()
+	m.probe.Close()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cpu := m.last.CPUTime
+	if state != nil {
+		cpu = max(cpu, state.UserTime()+state.SystemTime())
+	}
+	execution.CPUTimeMs = cpu.Milliseconds()
+	if wall := time.Since(m.startedAt); wall > 0 {
+		execution.CPUPercentAvg = float64(cpu) / float64(wall) * 100
+	}
+	execution.CPUPercentPeak = max(m.cpuPeak, execution.CPUPercentAvg)
+	execution.MemoryPeakBytes = m.memPeak
+	if m.samples > 0 {
+		execution.MemoryAvgBytes = m.memSum / int64(m.samples)
+	}
+	execution.IOReadBytes = m.last.ReadBytes
+	execution.IOWriteBytes = m.last.WriteBytes
+	execution.ResourceSamples = m.samples
+	m.memoryKilled = m.memoryKilled || memoryHit
+}
+
+// MemoryLimitHit 是否因超出内存上限被终止（Stop 之后调用）
+func (m *resourceMonitor) MemoryLimitHit() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.memoryKilled
+}
+
+// ResourceTrend 查询任务最近 limit 次已结束执行的资源占用（按开始时间升序，便于绘制趋势）
+func ResourceTrend(taskID string, limit int) ([]models.ResourcePoint, error) {
+	if limit <= 0 || limit > maxResourceTrendLimit {
+		limit = maxResourceTrendLimit
+	}
+
+	var points []models.ResourcePoint
+	err := database.GetDB().
+		Model(&models.Execution{}).
+		Where("task_id = ? AND end_time IS NOT NULL", taskID).
+		Order("start_time DESC").
+		Limit(limit).
+		Find(&points).Error
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+	return points, nil
+}
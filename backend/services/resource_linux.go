@@ -0,0 +1,247 @@
+//go:build linux
+
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// linuxClockTicks /proc/<pid>/stat 中 CPU 时间的单位（USER_HZ，Linux 上固定为 100）
+const linuxClockTicks = 100
+
+// cgroupRoot ScriptGuard 在 cgroup v2 层级下使用的目录（需有写权限）
+const cgroupRoot = "/sys/fs/cgroup/scriptguard"
+
+// cgroupCPUPeriod cpu.max 的周期（微秒）
+const cgroupCPUPeriod = 100000
+
+// procGroupProbe 通过 /proc 采样进程组（执行器以独立进程组启动脚本）
+type procGroupProbe struct {
+	pgid   int
+	cgroup string // 本次执行的 cgroup 目录，未使用 cgroup 时为空
+}
+
+// newResourceProbe 应用资源限制：内存、CPU、进程数使用 cgroup v2，不可用时内存由采样监控超限终止；
+// 打开文件数使用 RLIMIT_NOFILE，调度优先级作用于整个进程组
+// 限制在进程启动后才施加，启动瞬间派生的子进程可能不受 rlimit 约束
+func newResourceProbe(p *os.Process, limits resourceLimits) (resourceProbe, error) {
+	probe := &procGroupProbe{pgid: p.Pid}
+	if limits.IsZero() {
+		return probe, nil
+	}
+
+	var problems []string
+	if limits.MemoryBytes > 0 || limits.CPUPercent > 0 || limits.MaxProcesses > 0 {
+		dir, err := createCgroup(p.Pid, limits)
+		if err == nil {
+			probe.cgroup = dir
+		} else {
+			// RLIMIT_AS 限制的是虚拟地址空间，容易误伤 numpy 等预留大量地址的库，不作为降级方案
+			log.Printf("cgroup 不可用(pid=%d): %v，内存上限改由采样监控执行", p.Pid, err)
+			if limits.CPUPercent > 0 {
+				problems = append(problems, "CPU 上限需要 cgroup v2")
+			}
+			if limits.MaxProcesses > 0 {
+				problems = append(problems, "进程数上限需要 cgroup v2")
+			}
+		}
+	}
+	if limits.MaxOpenFiles > 0 {
+		if err := setRlimit(p.Pid, unix.RLIMIT_NOFILE, int64(limits.MaxOpenFiles)); err != nil {
+			problems = append(problems, "打开文件数上限: "+err.Error())
+		}
+	}
+	if limits.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PGRP, p.Pid, limits.Nice); err != nil {
+			problems = append(problems, "调度优先级: "+err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return probe, fmt.Errorf("部分资源限制未生效: %s", strings.Join(problems, "；"))
+	}
+	return probe, nil
+}
+
+// createCgroup 为本次执行创建 cgroup 并把进程移入
+func createCgroup(pid int, limits resourceLimits) (string, error) {
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err != nil {
+		return "", errors.New("未启用 cgroup v2")
+	}
+	if err := os.MkdirAll(cgroupRoot, 0755); err != nil {
+		return "", err
+	}
+	// 在父级开启所需控制器（已开启或无权限时忽略，后续写入限制时会报错）
+	_ = os.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte("+memory +cpu +pids"), 0644)
+
+	dir := filepath.Join(cgroupRoot, strconv.Itoa(pid))
+	if err := os.Mkdir(dir, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", err
+	}
+
+	write := func(name, value string) error {
+		return os.WriteFile(filepath.Join(dir, name), []byte(value), 0644)
+	}
+	var err error
+	if limits.MemoryBytes > 0 {
+		err = errors.Join(err, write("memory.max", strconv.FormatInt(limits.MemoryBytes, 10)))
+	}
+	if limits.CPUPercent > 0 {
+		quota := cgroupCPUPeriod * runtime.NumCPU() * limits.CPUPercent / 100
+		err = errors.Join(err, write("cpu.max", fmt.Sprintf("%d %d", max(quota, 1000), cgroupCPUPeriod)))
+	}
+	if limits.MaxProcesses > 0 {
+		err = errors.Join(err, write("pids.max", strconv.Itoa(limits.MaxProcesses)))
+	}
+	if err == nil {
+		err = write("cgroup.procs", strconv.Itoa(pid))
+	}
+	if err != nil {
+		_ = os.Remove(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// setRlimit 设置进程的资源上限（软硬限制相同）
+func setRlimit(pid, resource int, value int64) error {
+	limit := unix.Rlimit{Cur: uint64(value), Max: uint64(value)}
+	return unix.Prlimit(pid, resource, &limit, nil)
+}
+
+// MemoryLimitHit cgroup 内是否发生过 OOM Kill
+func (p *procGroupProbe) MemoryLimitHit() bool {
+	if p.cgroup == "" {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(p.cgroup, "memory.events"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			n, _ := strconv.Atoi(strings.TrimSpace(value))
+			return n > 0
+		}
+	}
+	return false
+}
+
+func (p *procGroupProbe) Sample() (resourceSample, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return resourceSample{}, err
+	}
+
+	var total resourceSample
+	found := false
+	pageSize := int64(os.Getpagesize())
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := readProcStat(pid)
+		if err != nil || stat.pgrp != p.pgid {
+			continue
+		}
+		found = true
+		// 已回收子进程的 CPU 时间计入父进程的 cutime/cstime
+		ticks := stat.utime + stat.stime + stat.cutime + stat.cstime
+		total.CPUTime += time.Duration(ticks) * time.Second / linuxClockTicks
+		total.Memory += stat.rss * pageSize
+
+		if read, write, err := readProcIO(pid); err == nil {
+			total.ReadBytes += read
+			total.WriteBytes += write
+		}
+	}
+	if !found {
+		return resourceSample{}, errors.New("进程组已退出")
+	}
+	return total, nil
+}
+
+func (p *procGroupProbe) Close() {
+	// 进程全部退出后 cgroup 才能删除
+	if p.cgroup != "" {
+		if err := os.Remove(p.cgroup); err != nil {
+			log.Printf("删除 cgroup 失败(path=%s): %v", p.cgroup, err)
+		}
+	}
+}
+
+// procStat /proc/<pid>/stat 中用到的字段
+type procStat struct {
+	pgrp                         int
+	utime, stime, cutime, cstime int64
+	rss                          int64
+}
+
+// readProcStat 解析 /proc/<pid>/stat（进程名可能含空格和括号，从最后一个 ')' 之后开始按空格切分）
+func readProcStat(pid int) (procStat, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+	s := string(data)
+	end := strings.LastIndexByte(s, ')')
+	if end < 0 {
+		return procStat{}, errors.New("stat 格式错误")
+	}
+	// fields[0] 为第 3 个字段（state）
+	fields := strings.Fields(s[end+1:])
+	if len(fields) < 22 {
+		return procStat{}, errors.New("stat 字段不足")
+	}
+	num := func(i int) int64 {
+		n, _ := strconv.ParseInt(fields[i-3], 10, 64)
+		return n
+	}
+	return procStat{
+		pgrp:   int(num(5)),
+		utime:  num(14),
+		stime:  num(15),
+		cutime: num(16),
+		cstime: num(17),
+		rss:    num(24),
+	}, nil
+}
+
+// readProcIO 读取 /proc/<pid>/io 中实际落盘的读写字节
+func readProcIO(pid int) (int64, int64, error) {
+	f, err := os.Open(filepath.Join("/proc", strconv.Itoa(pid), "io"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var read, write int64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		n, _ := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		switch key {
+		case "read_bytes":
+			read = n
+		case "write_bytes":
+			write = n
+		}
+	}
+	return read, write, scanner.Err()
+}
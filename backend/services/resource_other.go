@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+
+package services
+
+import "os"
+
+// newResourceProbe 当前系统不支持进程树采样与资源限制，仅记录进程退出时的 CPU 时间
+func newResourceProbe(_ *os.Process, _ resourceLimits) (resourceProbe, error) {
+	return nil, nil
+}
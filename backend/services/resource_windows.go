@@ -0,0 +1,267 @@
+//go:build windows
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procK32GetProcessMemoryInfo = windows.NewLazySystemDLL("kernel32.dll").NewProc("K32GetProcessMemoryInfo")
+
+// maxJobProcessIDs 单次查询作业内进程 ID 的上限
+const maxJobProcessIDs = 512
+
+// 作业 CPU 限速（JOBOBJECT_CPU_RATE_CONTROL_INFORMATION）
+const (
+	jobObjectCPURateControlEnable  = 0x1
+	jobObjectCPURateControlHardCap = 0x4
+)
+
+// jobCPURateControl JOBOBJECT_CPU_RATE_CONTROL_INFORMATION（CpuRate 单位为 1/100 %）
+type jobCPURateControl struct {
+	ControlFlags uint32
+	CpuRate      uint32
+}
+
+// jobObjectMsgJobMemoryLimit 作业内存超限时投递到完成端口的消息（JOB_OBJECT_MSG_JOB_MEMORY_LIMIT）
+const jobObjectMsgJobMemoryLimit = 10
+
+// jobAssociateCompletionPort JOBOBJECT_ASSOCIATE_COMPLETION_PORT
+type jobAssociateCompletionPort struct {
+	CompletionKey  uintptr
+	CompletionPort windows.Handle
+}
+
+// jobBasicAndIOAccounting JOBOBJECT_BASIC_AND_IO_ACCOUNTING_INFORMATION
+type jobBasicAndIOAccounting struct {
+	TotalUserTime             int64 // 100ns
+	TotalKernelTime           int64 // 100ns
+	ThisPeriodTotalUserTime   int64
+	ThisPeriodTotalKernelTime int64
+	TotalPageFaultCount       uint32
+	TotalProcesses            uint32
+	ActiveProcesses           uint32
+	TotalTerminatedProcesses  uint32
+	IoInfo                    windows.IO_COUNTERS
+}
+
+// jobProcessIDList JOBOBJECT_BASIC_PROCESS_ID_LIST
+type jobProcessIDList struct {
+	NumberOfAssignedProcesses uint32
+	NumberOfProcessIdsInList  uint32
+	ProcessIdList             [maxJobProcessIDs]uintptr
+}
+
+// processMemoryCounters PROCESS_MEMORY_COUNTERS
+type processMemoryCounters struct {
+	Cb                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// jobProbe 通过作业对象采样并限制进程树：cmd /c 之后启动的 conda、python 自动加入同一作业，
+// 作业的 CPU 与 I/O 计数包含已退出的子进程
+type jobProbe struct {
+	job windows.Handle
+	// port 接收作业通知的完成端口，仅设置内存上限时创建
+	port      windows.Handle
+	memoryHit bool
+}
+
+// newResourceProbe 创建作业对象并应用资源限制（打开文件数在 Windows 上不支持）
+// 进程以挂起状态启动（见 newScriptProcAttr），加入作业后由 resumeProcess 恢复，
+// 因此其派生的所有子进程都受作业限制
+func newResourceProbe(p *os.Process, limits resourceLimits) (resourceProbe, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建作业对象失败: %w", err)
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(p.Pid))
+	if err != nil {
+		windows.CloseHandle(job)
+		return nil, fmt.Errorf("打开进程失败: %w", err)
+	}
+	defer windows.CloseHandle(process)
+
+	probe := &jobProbe{job: job}
+
+	var problems []string
+	if err := probe.setLimits(limits); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if limits.MemoryBytes > 0 {
+		if err := probe.watchNotifications(); err != nil {
+			problems = append(problems, "内存超限通知: "+err.Error())
+		}
+	}
+	if limits.MaxOpenFiles > 0 {
+		problems = append(problems, "Windows 不支持打开文件数上限")
+	}
+
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		probe.Close()
+		return nil, fmt.Errorf("加入作业对象失败: %w", err)
+	}
+	if len(problems) > 0 {
+		return probe, fmt.Errorf("部分资源限制未生效: %s", strings.Join(problems, "；"))
+	}
+	return probe, nil
+}
+
+// setLimits 设置作业的内存、进程数、优先级与 CPU 限速
+func (p *jobProbe) setLimits(limits resourceLimits) error {
+	var info windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+	if limits.MemoryBytes > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_JOB_MEMORY
+		info.JobMemoryLimit = uintptr(limits.MemoryBytes)
+	}
+	if limits.MaxProcesses > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_ACTIVE_PROCESS
+		info.BasicLimitInformation.ActiveProcessLimit = uint32(limits.MaxProcesses)
+	}
+	if class := priorityClassForNice(limits.Nice); class != 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_PRIORITY_CLASS
+		info.BasicLimitInformation.PriorityClass = class
+	}
+
+	var errs []error
+	if info.BasicLimitInformation.LimitFlags != 0 {
+		if _, err := windows.SetInformationJobObject(p.job, windows.JobObjectExtendedLimitInformation,
+			uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+			errs = append(errs, fmt.Errorf("内存/进程数/优先级: %w", err))
+		}
+	}
+	if limits.CPUPercent > 0 {
+		rate := jobCPURateControl{
+			ControlFlags: jobObjectCPURateControlEnable | jobObjectCPURateControlHardCap,
+			CpuRate:      uint32(limits.CPUPercent * 100),
+		}
+		if _, err := windows.SetInformationJobObject(p.job, windows.JobObjectCpuRateControlInformation,
+			uintptr(unsafe.Pointer(&rate)), uint32(unsafe.Sizeof(rate))); err != nil {
+			errs = append(errs, fmt.Errorf("CPU 上限: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// priorityClassForNice 将 nice 值映射为 Windows 优先级类（0 表示不修改）
+func priorityClassForNice(nice int) uint32 {
+	switch {
+	case nice >= 10:
+		return windows.IDLE_PRIORITY_CLASS
+	case nice > 0:
+		return windows.BELOW_NORMAL_PRIORITY_CLASS
+	case nice <= -10:
+		return windows.HIGH_PRIORITY_CLASS
+	case nice < 0:
+		return windows.ABOVE_NORMAL_PRIORITY_CLASS
+	}
+	return 0
+}
+
+// watchNotifications 将作业关联到完成端口，以接收内存超限通知
+func (p *jobProbe) watchNotifications() error {
+	port, err := windows.CreateIoCompletionPort(windows.InvalidHandle, 0, 0, 1)
+	if err != nil {
+		return err
+	}
+	assoc := jobAssociateCompletionPort{CompletionKey: uintptr(p.job), CompletionPort: port}
+	if _, err := windows.SetInformationJobObject(p.job, windows.JobObjectAssociateCompletionPortInformation,
+		uintptr(unsafe.Pointer(&assoc)), uint32(unsafe.Sizeof(assoc))); err != nil {
+		windows.CloseHandle(port)
+		return err
+	}
+	p.port = port
+	return nil
+}
+
+// drainNotifications 取出完成端口中已投递的作业消息，记录是否出现过内存超限
+func (p *jobProbe) drainNotifications() {
+	if p.port == 0 {
+		return
+	}
+	for {
+		var msg uint32
+		var key uintptr
+		var overlapped *windows.Overlapped
+		if err := windows.GetQueuedCompletionStatus(p.port, &msg, &key, &overlapped, 0); err != nil {
+			return
+		}
+		if msg == jobObjectMsgJobMemoryLimit {
+			p.memoryHit = true
+		}
+	}
+}
+
+// MemoryLimitHit 作业是否收到过内存超限通知（JOB_OBJECT_MSG_JOB_MEMORY_LIMIT）
+func (p *jobProbe) MemoryLimitHit() bool {
+	p.drainNotifications()
+	return p.memoryHit
+}
+
+func (p *jobProbe) Sample() (resourceSample, error) {
+	// 定期取出通知，避免完成端口中的进程创建/退出消息堆积
+	p.drainNotifications()
+
+	var acct jobBasicAndIOAccounting
+	err := windows.QueryInformationJobObject(p.job, windows.JobObjectBasicAndIoAccountingInformation,
+		uintptr(unsafe.Pointer(&acct)), uint32(unsafe.Sizeof(acct)), nil)
+	if err != nil {
+		return resourceSample{}, err
+	}
+	sample := resourceSample{
+		CPUTime:    time.Duration(acct.TotalUserTime+acct.TotalKernelTime) * 100,
+		ReadBytes:  int64(acct.IoInfo.ReadTransferCount),
+		WriteBytes: int64(acct.IoInfo.WriteTransferCount),
+	}
+
+	// 内存：累加作业内各进程的工作集
+	var ids jobProcessIDList
+	err = windows.QueryInformationJobObject(p.job, windows.JobObjectBasicProcessIdList,
+		uintptr(unsafe.Pointer(&ids)), uint32(unsafe.Sizeof(ids)), nil)
+	if err != nil && err != windows.ERROR_MORE_DATA {
+		return sample, nil
+	}
+	for i := uint32(0); i < ids.NumberOfProcessIdsInList && i < maxJobProcessIDs; i++ {
+		sample.Memory += processWorkingSet(uint32(ids.ProcessIdList[i]))
+	}
+	return sample, nil
+}
+
+func (p *jobProbe) Close() {
+	if p.port != 0 {
+		windows.CloseHandle(p.port)
+	}
+	windows.CloseHandle(p.job)
+}
+
+// processWorkingSet 查询进程当前工作集大小，失败返回 0
+func processWorkingSet(pid uint32) int64 {
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return 0
+	}
+	defer windows.CloseHandle(process)
+
+	var counters processMemoryCounters
+	counters.Cb = uint32(unsafe.Sizeof(counters))
+	r, _, _ := procK32GetProcessMemoryInfo.Call(uintptr(process), uintptr(unsafe.Pointer(&counters)), uintptr(counters.Cb))
+	if r == 0 {
+		return 0
+	}
+	return int64(counters.WorkingSetSize)
+}
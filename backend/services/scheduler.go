@@ -1,6 +1,7 @@
 package services
 
 import (
+	"fmt"
 	"log"
 	"scriptguard/backend/database"
 	"scriptguard/backend/models"
@@ -89,23 +90,28 @@ func (s *SchedulerService) UpdateTask(task *models.Task) error {
 
 // executeTask 执行任务
 func (s *SchedulerService) executeTask(task *models.Task) {
-	// SG-003: 并发限制，达到上限时跳过本次触发
-	if !s.executor.TryExecute() {
-		log.Printf("并发达到上限，跳过本次触发(task_id=%s, task_name=%s)", task.ID, task.Name)
-		// 写一条警告日志到数据库
-		s.executor.SaveInfoLog("", task.ID, "并发达到上限，跳过本次定时触发")
-		return
+	// SG-003: 并发限制，槽位不足时按优先级排队；同一任务已在排队时跳过本次触发
+	if !s.executor.TryExecute(task) {
+		s.executor.SaveInfoLog("", task.ID, "并发达到上限，排队等待执行")
+		if err := s.executor.WaitExecute(task); err != nil {
+			log.Printf("跳过本次触发(task_id=%s, task_name=%s): %v", task.ID, task.Name, err)
+			// 写一条警告日志到数据库
+			s.executor.SaveInfoLog("", task.ID, fmt.Sprintf("跳过本次定时触发: %v", err))
+			s.notifier.NotifySkipped(task, err)
+			return
+		}
 	}
-	defer s.executor.ReleaseExecution()
+	defer s.executor.ReleaseExecution(task)
 
+	stopWatch := s.notifier.WatchLongRunning(task)
 	execution, err := s.executor.ExecuteScript(task)
+	stopWatch()
 
 	// 定时执行也写入执行历史，检查写库错误
 	if dbErr := database.GetDB().Create(execution).Error; dbErr != nil {
 		log.Printf("写入执行历史失败(task_id=%s, execution_id=%s): %v", task.ID, execution.ID, dbErr)
 	}
 
-	if err != nil && task.NotifyOnFailure {
-		s.notifier.NotifyFailure(task, execution, err)
-	}
+	// 按任务的通知规则发送失败、成功、恢复等通知
+	s.notifier.NotifyExecution(task, execution, err)
 }
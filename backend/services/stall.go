@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"scriptguard/backend/models"
+	"sync/atomic"
+	"time"
+)
+
+// 卡住检测限制
+const (
+	maxStallMinutes       = 24 * 60          // 最长检测窗口
+	maxStallCheckInterval = 30 * time.Second // 检查间隔上限
+	minStallCheckInterval = time.Second
+	stallCheckIntervalDiv = 4 // 检查间隔为窗口的 1/4
+)
+
+// StallHandler 检测到执行卡住时的回调（在独立 goroutine 中调用，execution 为快照）
+// 处理方式为终止进程时在终止尝试之后调用，killErr 为终止失败的原因
+type StallHandler func(task *models.Task, execution *models.Execution, idle time.Duration, killErr error)
+
+// ValidateStallSettings 校验任务的卡住检测配置
+func ValidateStallSettings(task *models.Task) error {
+	if task.StallMinutes < 0 || task.StallMinutes > maxStallMinutes {
+		return fmt.Errorf("无输出检测时间超出允许范围：0~%d 分钟", maxStallMinutes)
+	}
+	switch task.StallAction {
+	case "", models.StallActionNotify, models.StallActionKill:
+		return nil
+	}
+	return fmt.Errorf("未知的卡住处理方式: %s", task.StallAction)
+}
+
+// stallWatchdog 输出不活跃看门狗：stdout/stderr 连续无输出超过窗口时触发一次
+type stallWatchdog struct {
+	limit time.Duration
+	last  atomic.Int64 // 最近一次输出的 UnixNano
+	stop  chan struct{}
+	done  chan struct{}
+
+	// 以下字段仅在看门狗 goroutine 内写入，Stop 返回后可安全读取
+	stalledAt *time.Time
+	killed    bool
+}
+
+// newStallWatchdog 创建看门狗，minutes<=0 时返回 nil（所有方法对 nil 安全）
+func newStallWatchdog(minutes int) *stallWatchdog {
+	if minutes <= 0 {
+		return nil
+	}
+	w := &stallWatchdog{
+		limit: time.Duration(minutes) * time.Minute,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	w.Touch()
+	return w
+}
+
+// Touch 记录一次输出
+func (w *stallWatchdog) Touch() {
+	if w != nil {
+		w.last.Store(time.Now().UnixNano())
+	}
+}
+
+// Start 启动检测，卡住时调用 onStall，返回值表示是否已终止进程
+func (w *stallWatchdog) Start(onStall func(idle time.Duration) bool) {
+	if w == nil {
+		return
+	}
+	interval := min(max(w.limit/stallCheckIntervalDiv, minStallCheckInterval), maxStallCheckInterval)
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				idle := time.Since(time.Unix(0, w.last.Load()))
+				if idle < w.limit {
+					continue
+				}
+				now := NowBeijing()
+				w.stalledAt = &now
+				w.killed = onStall(idle)
+				// 每次执行只触发一次
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止检测并等待看门狗退出
+func (w *stallWatchdog) Stop() {
+	if w == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+}
+
+// StalledAt 检测到卡住的时间（未卡住为 nil）
+func (w *stallWatchdog) StalledAt() *time.Time {
+	if w == nil {
+		return nil
+	}
+	return w.stalledAt
+}
+
+// Killed 是否因卡住终止了进程
+func (w *stallWatchdog) Killed() bool {
+	return w != nil && w.killed
+}
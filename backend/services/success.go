@@ -0,0 +1,144 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"scriptguard/backend/models"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ValidateSuccessRules 校验任务的成功判定规则
+func ValidateSuccessRules(rules models.SuccessRules) error {
+	_, err := newSuccessMatcher(rules)
+	return err
+}
+
+// compiledOutputRule 编译后的输出匹配规则
+type compiledOutputRule struct {
+	models.OutputRule
+	re      *regexp.Regexp
+	matched bool
+}
+
+// successMatcher 在执行过程中逐行匹配输出，执行结束后给出判定结果（stdout/stderr 并发写入）
+type successMatcher struct {
+	mu    sync.Mutex
+	rules models.SuccessRules
+	out   []*compiledOutputRule
+}
+
+// newSuccessMatcher 编译成功判定规则
+func newSuccessMatcher(rules models.SuccessRules) (*successMatcher, error) {
+	m := &successMatcher{rules: rules}
+	for _, rule := range rules.OutputRules {
+		switch rule.Stream {
+		case "", string(models.LogLevelStdout), string(models.LogLevelStderr):
+		default:
+			return nil, fmt.Errorf("输出规则的输出流非法: %s", rule.Stream)
+		}
+		switch rule.Severity {
+		case "", models.RuleSeverityFailed, models.RuleSeverityWarning:
+		default:
+			return nil, fmt.Errorf("输出规则的结果级别非法: %s", rule.Severity)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("输出规则正则非法(%s): %w", rule.Pattern, err)
+		}
+		m.out = append(m.out, &compiledOutputRule{OutputRule: rule, re: re})
+	}
+	if err := ValidateArtifactPatterns(rules.RequiredFiles); err != nil {
+		return nil, fmt.Errorf("产出文件规则非法: %w", err)
+	}
+	return m, nil
+}
+
+// Observe 匹配一行输出
+func (m *successMatcher) Observe(stream models.LogLevel, line string) {
+	if m == nil || len(m.out) == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, rule := range m.out {
+		if rule.matched || (rule.Stream != "" && rule.Stream != string(stream)) {
+			continue
+		}
+		if rule.re.MatchString(line) {
+			rule.matched = true
+		}
+	}
+}
+
+// ExitCodeAllowed 退出码是否视为成功
+func (m *successMatcher) ExitCodeAllowed(code int) bool {
+	if m == nil || len(m.rules.ExitCodes) == 0 {
+		return code == 0
+	}
+	return slices.Contains(m.rules.ExitCodes, code)
+}
+
+// Evaluate 按输出规则与产出文件判定结果，返回状态及原因（满足全部规则时返回 success）
+func (m *successMatcher) Evaluate(task *models.Task, execution *models.Execution) (models.ExecutionStatus, []string) {
+	if m == nil {
+		return models.StatusSuccess, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var failures, warnings []string
+	for _, rule := range m.out {
+		target := "输出"
+		if rule.Stream != "" {
+			target = rule.Stream + " "
+		}
+		var reason string
+		switch {
+		case rule.MustMatch && !rule.matched:
+			reason = fmt.Sprintf("%s中未出现 %q", target, rule.Pattern)
+		case !rule.MustMatch && rule.matched:
+			reason = fmt.Sprintf("%s中出现了 %q", target, rule.Pattern)
+		default:
+			continue
+		}
+		if rule.Severity == models.RuleSeverityWarning {
+			warnings = append(warnings, reason)
+		} else {
+			failures = append(failures, reason)
+		}
+	}
+
+	since := execution.StartTime.Add(-artifactMtimeTolerance)
+	for _, pattern := range m.rules.RequiredFiles {
+		if !requiredFileProduced(resolveTaskPath(task, pattern), since) {
+			failures = append(failures, fmt.Sprintf("未生成产出文件 %s", strings.TrimSpace(pattern)))
+		}
+	}
+
+	switch {
+	case len(failures) > 0:
+		return models.StatusFailed, append(failures, warnings...)
+	case len(warnings) > 0:
+		return models.StatusWarning, warnings
+	}
+	return models.StatusSuccess, nil
+}
+
+// requiredFileProduced 是否存在匹配且在 since 之后修改过的文件
+func requiredFileProduced(pattern string, since time.Time) bool {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return false
+	}
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() && !info.ModTime().Before(since) {
+			return true
+		}
+	}
+	return false
+}
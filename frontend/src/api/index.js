@@ -1,4 +1,5 @@
 // Wails 运行时导入
+import { Events } from '@wailsio/runtime'
 import { App } from '../../bindings/scriptguard/backend'
 
 const {
@@ -8,13 +9,34 @@ const {
   UpdateTask,
   DeleteTask,
   ExecuteTaskNow,
+  GetQueuedRuns,
+  GetPools,
+  CreatePool,
+  UpdatePool,
+  DeletePool,
   GetExecutions,
+  ListExecutions,
   GetLogs,
+  ListLogs,
+  SearchLogs,
+  GetResourceTrend,
+  ListArtifacts,
+  OpenArtifact,
   GetConfig,
   GetAllConfig,
   UpdateConfig,
   SelectScriptFile,
   TestNotification,
+  GetChannels,
+  GetChannelTypes,
+  CreateChannel,
+  UpdateChannel,
+  DeleteChannel,
+  TestChannel,
+  ListNotifications,
+  RetryNotification,
+  GetDigest,
+  SendDigest,
   ExportDebugLogs,
   GetAutoStartEnabled,
   SetAutoStartEnabled
@@ -22,6 +44,11 @@ const {
 
 // API 封装
 export const api = {
+  // 订阅执行的实时上报（type 为 progress / metric / finished），返回取消订阅函数
+  onExecutionReport(callback) {
+    return Events.On('execution:report', (event) => callback(event.data))
+  },
+
   // 环境相关
   async getEnvironments() {
     return await GetEnvironments()
@@ -48,16 +75,68 @@ export const api = {
     return await ExecuteTaskNow(taskId)
   },
 
+  // 排队中的执行（按获得槽位的先后顺序）
+  async getQueuedRuns() {
+    return await GetQueuedRuns()
+  },
+
+  // 资源池相关
+  async getPools() {
+    return await GetPools()
+  },
+
+  async createPool(pool) {
+    return await CreatePool(pool)
+  },
+
+  async updatePool(pool) {
+    return await UpdatePool(pool)
+  },
+
+  async deletePool(name) {
+    return await DeletePool(name)
+  },
+
   // 执行历史相关
   async getExecutions(taskId = '', limit = 100) {
     return await GetExecutions(taskId, limit)
   },
 
+  // 游标分页（query: { task_id, statuses, start, end, before, after, limit }）
+  async listExecutions(query = {}) {
+    return await ListExecutions(query)
+  },
+
   // 日志相关
   async getLogs(executionId = '', taskId = '', limit = 1000) {
     return await GetLogs(executionId, taskId, limit)
   },
 
+  // 游标分页（query: { execution_id, task_id, levels, start, end, before, after, limit }）
+  async listLogs(query = {}) {
+    return await ListLogs(query)
+  },
+
+  // 全文搜索日志（timeRange: { start, end }，cursor 为上一页的 next_cursor）
+  // 仅检索数据库存储的日志，skipped_file_executions 为使用文件存储、未参与检索的执行数
+  async searchLogs(query, taskIds = [], levels = [], timeRange = {}, cursor = '') {
+    return await SearchLogs(query, taskIds, levels, timeRange, cursor)
+  },
+
+  // 资源占用趋势（按开始时间升序）
+  async getResourceTrend(taskId, limit = 30) {
+    return await GetResourceTrend(taskId, limit)
+  },
+
+  // 执行产物
+  async listArtifacts(executionId) {
+    return await ListArtifacts(executionId)
+  },
+
+  async openArtifact(artifactId) {
+    return await OpenArtifact(artifactId)
+  },
+
   // 配置相关
   async getConfig(key) {
     return await GetConfig(key)
@@ -81,6 +160,49 @@ export const api = {
     return await TestNotification(target, webhook)
   },
 
+  // 通知渠道相关
+  async getChannels() {
+    return await GetChannels()
+  },
+
+  async getChannelTypes() {
+    return await GetChannelTypes()
+  },
+
+  async createChannel(channel) {
+    return await CreateChannel(channel)
+  },
+
+  async updateChannel(channel) {
+    return await UpdateChannel(channel)
+  },
+
+  async deleteChannel(channelId) {
+    return await DeleteChannel(channelId)
+  },
+
+  async testChannel(channel) {
+    return await TestChannel(channel)
+  },
+
+  // 告警发送记录
+  async listNotifications(status = '', limit = 100) {
+    return await ListNotifications(status, limit)
+  },
+
+  async retryNotification(notificationId) {
+    return await RetryNotification(notificationId)
+  },
+
+  // 执行汇总（period: daily / weekly）
+  async getDigest(period = 'daily') {
+    return await GetDigest(period)
+  },
+
+  async sendDigest(period = 'daily') {
+    return await SendDigest(period)
+  },
+
   // 导出调试日志
   async exportDebugLogs(frontendLogs = '') {
     return await ExportDebugLogs(frontendLogs)
@@ -20,6 +20,7 @@ const messages = {
       success: '成功',
       failed: '失败',
       running: '运行中',
+      warning: '警告',
       unknown: '未知',
       enabled: '已启用',
       disabled: '已停用',
@@ -104,7 +105,8 @@ const messages = {
       export: '导出',
       waitingForLogs: '等待日志...',
       retry: '重试',
-      savedTo: '已保存至'
+      savedTo: '已保存至',
+      loadEarlier: '加载更早的日志'
     },
 
     // 执行历史
@@ -116,7 +118,10 @@ const messages = {
       endDate: '结束日期',
       recentActivity: '最近活动',
       noRecords: '暂无记录',
-      exitCode: '退出码'
+      exitCode: '退出码',
+      newer: '较新',
+      older: '更早',
+      total: '共'
     },
 
     // 设置
@@ -166,6 +171,7 @@ const messages = {
         keepLogs: '保留天数',
         execution: '执行配置',
         maxConcurrency: '最大并发数',
+        maxConcurrencyDesc: '槽位不足时，定时触发会排队等待而不是跳过，可能延后执行；调小时不能低于任何任务的权重',
         timeout: '超时时间（秒）'
       },
 
@@ -210,6 +216,7 @@ const messages = {
       success: 'Success',
       failed: 'Failed',
       running: 'Running',
+      warning: 'Warning',
       unknown: 'Unknown',
       enabled: 'Enabled',
       disabled: 'Disabled',
@@ -290,7 +297,8 @@ const messages = {
       export: 'Export',
       waitingForLogs: 'Waiting for logs...',
       retry: 'Retry',
-      savedTo: 'Saved to'
+      savedTo: 'Saved to',
+      loadEarlier: 'Load earlier logs'
     },
 
     history: {
@@ -301,7 +309,10 @@ const messages = {
       endDate: 'End',
       recentActivity: 'Recent Activity',
       noRecords: 'No records',
-      exitCode: 'Exit'
+      exitCode: 'Exit',
+      newer: 'Newer',
+      older: 'Older',
+      total: 'Total'
     },
 
     settings: {
@@ -346,6 +357,7 @@ const messages = {
         keepLogs: 'Keep logs for (days)',
         execution: 'Execution',
         maxConcurrency: 'Max Concurrency',
+        maxConcurrencyDesc: 'When all slots are busy, scheduled runs wait in the queue instead of being skipped and may start late. It cannot be set below any task\'s weight',
         timeout: 'Timeout (Seconds)'
       },
 